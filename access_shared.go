@@ -0,0 +1,174 @@
+package mediabrowser
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// LibraryAccess is how much of a library a user can see.
+type LibraryAccess string
+
+const (
+	AccessFull    LibraryAccess = "full"
+	AccessPartial LibraryAccess = "partial" // Some subfolders are excluded (Emby).
+	AccessNone    LibraryAccess = "none"
+)
+
+// AccessMatrixLibrary is a column of an AccessMatrix.
+type AccessMatrixLibrary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AccessMatrixUser is a row of an AccessMatrix. Access is keyed by library name.
+type AccessMatrixUser struct {
+	ID       string                   `json:"id"`
+	Name     string                   `json:"name"`
+	Disabled bool                     `json:"disabled"` // Disabled users can't log in, regardless of their access.
+	Access   map[string]LibraryAccess `json:"access"`
+}
+
+// AccessMatrix describes which users can see which libraries, according to their policies.
+type AccessMatrix struct {
+	Libraries []AccessMatrixLibrary `json:"libraries"`
+	Users     []AccessMatrixUser    `json:"users"`
+}
+
+// LibraryAccessMatrix builds an AccessMatrix for every user and library on the server. See BuildAccessMatrix.
+func (mb *MediaBrowser) LibraryAccessMatrix() (AccessMatrix, error) {
+	return mb.LibraryAccessMatrixContext(context.Background())
+}
+
+// LibraryAccessMatrixContext is LibraryAccessMatrix with a context controlling the requests.
+func (mb *MediaBrowser) LibraryAccessMatrixContext(ctx context.Context) (AccessMatrix, error) {
+	users, err := mb.GetUsersContext(ctx, false)
+	if err != nil {
+		return AccessMatrix{}, err
+	}
+	libraries, _, err := mb.GetLibrariesContext(ctx)
+	if err != nil {
+		return AccessMatrix{}, err
+	}
+	return BuildAccessMatrix(users, libraries), nil
+}
+
+// BuildAccessMatrix works out each user's access to each library from their policy:
+// EnableAllFolders or a library in EnabledFolders gives full access, unless it's in BlockedMediaFolders (Jellyfin).
+// Full access becomes partial if any of the library's subfolders are in ExcludedSubFolders (Emby, stored as "<library ID>_<subfolder ID>").
+func BuildAccessMatrix(users []User, libraries []VirtualFolder) AccessMatrix {
+	matrix := AccessMatrix{
+		Libraries: make([]AccessMatrixLibrary, len(libraries)),
+		Users:     make([]AccessMatrixUser, len(users)),
+	}
+	for i, library := range libraries {
+		matrix.Libraries[i] = AccessMatrixLibrary{ID: library.ItemId, Name: library.Name}
+	}
+	for i, user := range users {
+		row := AccessMatrixUser{
+			ID:       user.ID,
+			Name:     user.Name,
+			Disabled: user.Policy.IsDisabled,
+			Access:   make(map[string]LibraryAccess, len(libraries)),
+		}
+		for _, library := range libraries {
+			folders := user.Policy.libraryPolicy(libraryIDs(library), library.Name)
+			access := AccessNone
+			if folders.rule == RuleVisible {
+				access = AccessFull
+				if len(folders.excluded) != 0 {
+					access = AccessPartial
+				}
+			}
+			row.Access[library.Name] = access
+		}
+		matrix.Users[i] = row
+	}
+	return matrix
+}
+
+// libraryPolicy is how a policy treats one library, shared by BuildAccessMatrix and Policy.ItemVisibility.
+type libraryPolicy struct {
+	rule     VisibilityRule  // RuleVisible if the library can be seen, otherwise RuleLibraryBlocked or RuleLibraryNotEnabled.
+	excluded map[string]bool // Normalized IDs of the library's subfolders in ExcludedSubFolders.
+}
+
+// libraryPolicy works out how the policy treats the library with the given IDs (see libraryIDs) and name, which can be empty if unknown.
+func (p Policy) libraryPolicy(ids []string, name string) libraryPolicy {
+	isLibrary := func(id string) bool {
+		id = normalizeID(id)
+		for _, other := range ids {
+			if id == other {
+				return true
+			}
+		}
+		return false
+	}
+	folders := libraryPolicy{rule: RuleLibraryNotEnabled, excluded: map[string]bool{}}
+	for _, folder := range p.ExcludedSubFolders {
+		if s, ok := folder.(string); ok {
+			if sep := strings.Index(s, "_"); sep != -1 && isLibrary(s[:sep]) {
+				folders.excluded[normalizeID(s[sep+1:])] = true
+			}
+		}
+	}
+	for _, folder := range p.BlockedMediaFolders {
+		// Older servers stored names here rather than IDs.
+		if s, ok := folder.(string); ok && (isLibrary(s) || (name != "" && strings.EqualFold(s, name))) {
+			folders.rule = RuleLibraryBlocked
+			return folders
+		}
+	}
+	if p.EnableAllFolders {
+		folders.rule = RuleVisible
+	}
+	for _, id := range p.EnabledFolders {
+		if isLibrary(id) {
+			folders.rule = RuleVisible
+		}
+	}
+	return folders
+}
+
+// UsersWithAccess returns the users with full or partial access to the named library.
+func (m AccessMatrix) UsersWithAccess(library string) []AccessMatrixUser {
+	users := []AccessMatrixUser{}
+	for _, user := range m.Users {
+		if access, ok := user.Access[library]; ok && access != AccessNone {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// WriteCSV writes the matrix as CSV, one row per user and one column per library.
+func (m AccessMatrix) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := []string{"User ID", "Username", "Disabled"}
+	for _, library := range m.Libraries {
+		header = append(header, library.Name)
+	}
+	out.Write(header)
+	for _, user := range m.Users {
+		disabled := "false"
+		if user.Disabled {
+			disabled = "true"
+		}
+		row := []string{user.ID, user.Name, disabled}
+		for _, library := range m.Libraries {
+			row = append(row, string(user.Access[library.Name]))
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the matrix as indented JSON.
+func (m AccessMatrix) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
@@ -0,0 +1,57 @@
+package mediabrowser
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestAccessMatrix(t *testing.T) {
+	libraries := []VirtualFolder{
+		{Name: "Movies", ItemId: "f137a2dd21bbc1b99aa5c0f6bf02a805"},
+		{Name: "4K", ItemId: "a656b907eb3a73532e40e44b968d0225"},
+	}
+	users := []User{
+		{ID: "aaaaaaaaaaaa", Name: "alice", Policy: Policy{EnableAllFolders: true}},
+		{ID: "bbbbbbbbbbbb", Name: "bob", Policy: Policy{EnabledFolders: []string{"f137a2dd-21bb-c1b9-9aa5-c0f6bf02a805"}}},
+		{ID: "cccccccccccc", Name: "carol", Policy: Policy{EnableAllFolders: true, BlockedMediaFolders: []interface{}{"a656b907eb3a73532e40e44b968d0225"}}},
+		{ID: "dddddddddddd", Name: "dave", Policy: Policy{EnableAllFolders: true, IsDisabled: true, ExcludedSubFolders: []interface{}{"f137a2dd21bbc1b99aa5c0f6bf02a805_1234"}}},
+	}
+	m := BuildAccessMatrix(users, libraries)
+	want := map[string][2]LibraryAccess{
+		"alice": {AccessFull, AccessFull},
+		"bob":   {AccessFull, AccessNone},
+		"carol": {AccessFull, AccessNone},
+		"dave":  {AccessPartial, AccessFull},
+	}
+	for _, user := range m.Users {
+		if got := [2]LibraryAccess{user.Access["Movies"], user.Access["4K"]}; got != want[user.Name] {
+			t.Errorf("%s: got %v, want %v", user.Name, got, want[user.Name])
+		}
+	}
+	if who := m.UsersWithAccess("4K"); len(who) != 2 || who[0].Name != "alice" || who[1].Name != "dave" {
+		t.Errorf("unexpected users with access to 4K: %+v", who)
+	}
+
+	var csv bytes.Buffer
+	if err := m.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	const wantCSV = "User ID,Username,Disabled,Movies,4K\n" +
+		"aaaaaaaaaaaa,alice,false,full,full\n" +
+		"bbbbbbbbbbbb,bob,false,full,none\n" +
+		"cccccccccccc,carol,false,full,none\n" +
+		"dddddddddddd,dave,true,partial,full\n"
+	if csv.String() != wantCSV {
+		t.Errorf("unexpected CSV:\n%s", csv.String())
+	}
+
+	var out bytes.Buffer
+	if err := m.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded AccessMatrix
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded.Users[3].Access["Movies"] != AccessPartial {
+		t.Errorf("JSON didn't round-trip: %v\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,98 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// GetActivityLog returns a page of the server's activity log, newest first.
+func (mb *MediaBrowser) GetActivityLog(query ActivityLogQuery) (ActivityLog, error) {
+	return mb.GetActivityLogContext(context.Background(), query)
+}
+
+// GetActivityLogContext is GetActivityLog with a context controlling the request.
+func (mb *MediaBrowser) GetActivityLogContext(ctx context.Context, query ActivityLogQuery) (ActivityLog, error) {
+	u := mb.activityLogURL(query)
+	var log ActivityLog
+	data, status, err := mb.getJSON(ctx, u, nil, &log, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return ActivityLog{}, err
+	}
+	return log, nil
+}
+
+// activityLogURL returns the address of the activity log entries matching the query.
+func (mb *MediaBrowser) activityLogURL(query ActivityLogQuery) string {
+	params := url.Values{}
+	if query.StartIndex != 0 {
+		params.Set("startIndex", strconv.Itoa(query.StartIndex))
+	}
+	if query.Limit != 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if !query.MinDate.IsZero() {
+		params.Set("minDate", query.MinDate.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	if query.HasUserID != nil {
+		params.Set("hasUserId", strconv.FormatBool(*query.HasUserID))
+	}
+	u := fmt.Sprintf("%s/System/ActivityLog/Entries", mb.Server)
+	if len(params) != 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
+
+// ActivityLogIterator pages through the activity log, see MediaBrowser.ActivityLogIterator.
+type ActivityLogIterator struct {
+	p     *pager
+	entry ActivityLogEntry
+}
+
+// ActivityLogIterator returns an iterator over every entry in the activity log matching the query, fetching query.Limit (default 100) entries at a time.
+// Use like:
+//
+//	it := mb.ActivityLogIterator(ActivityLogQuery{})
+//	defer it.Close()
+//	for it.Next() {
+//		entry := it.Item()
+//	}
+//	if it.Err() != nil { ... }
+func (mb *MediaBrowser) ActivityLogIterator(query ActivityLogQuery) *ActivityLogIterator {
+	return mb.ActivityLogIteratorContext(context.Background(), query)
+}
+
+// ActivityLogIteratorContext is ActivityLogIterator with a context controlling the requests.
+func (mb *MediaBrowser) ActivityLogIteratorContext(ctx context.Context, query ActivityLogQuery) *ActivityLogIterator {
+	return &ActivityLogIterator{p: newPager(ctx, mb, query.StartIndex, query.Limit, func(startIndex, limit int) string {
+		query.StartIndex, query.Limit = startIndex, limit
+		return mb.activityLogURL(query)
+	})}
+}
+
+// Next advances to the next entry, fetching the next page if necessary. It returns false when there are no more entries, or an error occurred.
+func (it *ActivityLogIterator) Next() bool {
+	it.entry = ActivityLogEntry{}
+	return it.p.next(&it.entry)
+}
+
+// Item returns the current entry.
+func (it *ActivityLogIterator) Item() ActivityLogEntry {
+	return it.entry
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *ActivityLogIterator) Err() error {
+	return it.p.err
+}
+
+// Close releases the current response. Only needed if you stop before Next returns false.
+func (it *ActivityLogIterator) Close() {
+	it.p.close()
+	it.p.done = true
+}
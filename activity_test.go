@@ -0,0 +1,54 @@
+package mediabrowser
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestActivityLogIterator(t *testing.T) {
+	const total = 25
+	requests := 0
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/System/ActivityLog/Entries" {
+			return
+		}
+		requests++
+		q := r.URL.Query()
+		if q.Get("hasUserId") != "true" || q.Get("minDate") != "2024-01-01T00:00:00.000Z" {
+			t.Errorf("filters sent incorrectly: %v", q)
+		}
+		start, _ := strconv.Atoi(q.Get("startIndex"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		items := ""
+		for i := start; i < start+limit && i < total; i++ {
+			if items != "" {
+				items += ","
+			}
+			items += fmt.Sprintf(`{"Id":%d,"Type":"SessionStarted","UserId":"u1","Date":"2024-02-01T00:00:00.0000000Z","Severity":"Information"}`, i)
+		}
+		fmt.Fprintf(w, `{"Items":[%s],"TotalRecordCount":%d,"StartIndex":%d}`, items, total, start)
+	}))
+
+	hasUser := true
+	it := mb.ActivityLogIterator(ActivityLogQuery{
+		Limit:     10,
+		MinDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		HasUserID: &hasUser,
+	})
+	var n int64
+	for it.Next() {
+		if entry := it.Item(); entry.ID != n || entry.Date.Month() != time.February {
+			t.Fatalf("unexpected entry %+v at %d", entry, n)
+		}
+		n++
+	}
+	if it.Err() != nil {
+		t.Fatalf("iteration failed: %v", it.Err())
+	}
+	if n != total || requests != 3 {
+		t.Errorf("got %d entries in %d requests, expected %d in 3", n, requests, total)
+	}
+}
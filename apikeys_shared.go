@@ -0,0 +1,81 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+type apiKeysResult struct {
+	Items            []AuthenticationInfo `json:"Items"`
+	TotalRecordCount int                  `json:"TotalRecordCount"`
+}
+
+// GetAPIKeys returns all API keys on the server. Requires admin authentication.
+func (mb *MediaBrowser) GetAPIKeys() ([]AuthenticationInfo, error) {
+	return mb.GetAPIKeysContext(context.Background())
+}
+
+// GetAPIKeysContext is GetAPIKeys with a context controlling the request.
+func (mb *MediaBrowser) GetAPIKeysContext(ctx context.Context) ([]AuthenticationInfo, error) {
+	url := fmt.Sprintf("%s/Auth/Keys", mb.Server)
+	var result apiKeysResult
+	data, status, err := mb.getJSON(ctx, url, nil, &result, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// CreateAPIKey creates a new API key for the given app name, and returns it.
+// Neither server returns the new key directly, so it is found by searching for the newest key with the same app name.
+func (mb *MediaBrowser) CreateAPIKey(app string) (AuthenticationInfo, error) {
+	return mb.CreateAPIKeyContext(context.Background(), app)
+}
+
+// CreateAPIKeyContext is CreateAPIKey with a context controlling the requests.
+func (mb *MediaBrowser) CreateAPIKeyContext(ctx context.Context, app string) (AuthenticationInfo, error) {
+	u := fmt.Sprintf("%s/Auth/Keys?app=%s", mb.Server, url.QueryEscape(app))
+	_, status, err := mb.post(ctx, u, nil, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return AuthenticationInfo{}, err
+	}
+	keys, err := mb.GetAPIKeysContext(ctx)
+	if err != nil {
+		return AuthenticationInfo{}, err
+	}
+	newest := -1
+	for i, key := range keys {
+		if key.AppName != app {
+			continue
+		}
+		if newest == -1 || key.DateCreated.After(keys[newest].DateCreated.Time) {
+			newest = i
+		}
+	}
+	if newest == -1 {
+		return AuthenticationInfo{}, NotFound
+	}
+	return keys[newest], nil
+}
+
+// RevokeAPIKey revokes the given API key (the AccessToken, not the ID).
+func (mb *MediaBrowser) RevokeAPIKey(key string) error {
+	return mb.RevokeAPIKeyContext(context.Background(), key)
+}
+
+// RevokeAPIKeyContext is RevokeAPIKey with a context controlling the request.
+func (mb *MediaBrowser) RevokeAPIKeyContext(ctx context.Context, key string) error {
+	u := fmt.Sprintf("%s/Auth/Keys/%s", mb.Server, url.PathEscape(key))
+	data, status, err := mb.delete(ctx, u)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package mediabrowser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores raw API responses (the user and library lists) for a limited time.
+// By default there's none, and each MediaBrowser only keeps the decoded lists itself. A shared cache (e.g. a FileCache, or one MemoryCache
+// given to several instances) lets instances, or restarts of the same one, reuse each other's results rather than all querying the server. See SetCache.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the data stored under key and when it expires. ok is false if there's nothing stored, or it has expired.
+	Get(key string) (data []byte, expiry time.Time, ok bool)
+	// Set stores data under key for the given duration.
+	Set(key string, data []byte, ttl time.Duration) error
+	// Invalidate removes anything stored under key.
+	Invalidate(key string) error
+}
+
+type cacheEntry struct {
+	Data   []byte    `json:"data"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// MemoryCache is a Cache held in memory. The zero value is not usable, see NewMemoryCache.
+type MemoryCache struct {
+	lock    sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]cacheEntry{}}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) ([]byte, time.Time, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || !entry.Expiry.After(time.Now()) {
+		return nil, time.Time{}, false
+	}
+	return entry.Data, entry.Expiry, true
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(key string, data []byte, ttl time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = cacheEntry{Data: data, Expiry: time.Now().Add(ttl)}
+	return nil
+}
+
+// Invalidate implements Cache.
+func (c *MemoryCache) Invalidate(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// FileCache is a Cache stored as files in a directory, so it survives restarts and can be shared between processes.
+// Each key is stored in its own file, which is replaced atomically when written.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache storing its files in dir, which is created if it doesn't exist.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+// Keys may contain anything (including the server's URL), so they're hashed to get a file name.
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache. Unreadable or corrupt files are treated as missing.
+func (c *FileCache) Get(key string) ([]byte, time.Time, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || !entry.Expiry.After(time.Now()) {
+		return nil, time.Time{}, false
+	}
+	return entry.Data, entry.Expiry, true
+}
+
+// Set implements Cache.
+func (c *FileCache) Set(key string, data []byte, ttl time.Duration) error {
+	out, err := json.Marshal(cacheEntry{Data: data, Expiry: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it over the old one, so readers never see a partial file.
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(out)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Invalidate implements Cache.
+func (c *FileCache) Invalidate(key string) error {
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// SetCache sets where user and library lists are cached, or with nil, stops using a shared cache. See Cache.
+func (mb *MediaBrowser) SetCache(cache Cache) {
+	mb.cacheLock.Lock()
+	defer mb.cacheLock.Unlock()
+	mb.cache = cache
+	mb.userCacheEntry, mb.libraryCacheEntry = time.Time{}, time.Time{}
+}
+
+func (mb *MediaBrowser) userCacheKey(public bool) string {
+	if public {
+		return mb.Server + "/users/public"
+	}
+	return mb.Server + "/users"
+}
+
+func (mb *MediaBrowser) libraryCacheKey() string {
+	return mb.Server + "/libraries"
+}
+
+// sharedCache returns mb.cache, which is nil unless SetCache has been called.
+func (mb *MediaBrowser) sharedCache() Cache {
+	mb.cacheLock.RLock()
+	defer mb.cacheLock.RUnlock()
+	return mb.cache
+}
+
+// responseBuffer returns a buffer to copy a response into for storeResponse, or nil if there's no shared cache to store it in.
+func (mb *MediaBrowser) responseBuffer() *bytes.Buffer {
+	if mb.sharedCache() == nil {
+		return nil
+	}
+	return &bytes.Buffer{}
+}
+
+// cachedResponse returns the data stored under key in mb.cache, unless it's the same entry we last loaded (with the given expiry).
+// In that case the local copy has been expired on purpose (or patched), so the server should be asked again.
+func (mb *MediaBrowser) cachedResponse(key string, loaded time.Time) ([]byte, time.Time, bool) {
+	cache := mb.sharedCache()
+	if cache == nil {
+		return nil, time.Time{}, false
+	}
+	data, expiry, ok := cache.Get(key)
+	if !ok || expiry.Equal(loaded) {
+		return nil, time.Time{}, false
+	}
+	return data, expiry, true
+}
+
+// storeResponse stores the response copied into raw (see responseBuffer) under key in mb.cache, returning when it expires.
+// If raw is nil, or there's no shared cache, nothing is stored.
+func (mb *MediaBrowser) storeResponse(key string, raw *bytes.Buffer) time.Time {
+	cache := mb.sharedCache()
+	ttl := time.Minute * time.Duration(mb.cacheLength)
+	expiry := time.Now().Add(ttl)
+	if cache == nil || raw == nil {
+		return expiry
+	}
+	// The cache is an optimisation, so failing to store is ignored.
+	cache.Set(key, raw.Bytes(), ttl)
+	if _, stored, ok := cache.Get(key); ok {
+		expiry = stored
+	}
+	return expiry
+}
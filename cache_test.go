@@ -0,0 +1,79 @@
+package mediabrowser
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileCache(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := cache.Get("a"); ok {
+		t.Fatalf("empty cache returned a value")
+	}
+	if err := cache.Set("a", []byte("hello"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("b", []byte("expired"), -time.Second)
+	if data, expiry, ok := cache.Get("a"); !ok || string(data) != "hello" || !expiry.After(time.Now()) {
+		t.Errorf("unexpected value: %q, %v, %t", data, expiry, ok)
+	}
+	if _, _, ok := cache.Get("b"); ok {
+		t.Errorf("expired value returned")
+	}
+	if err := cache.Invalidate("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := cache.Get("a"); ok {
+		t.Errorf("invalidated value returned")
+	}
+	if err := cache.Invalidate("a"); err != nil {
+		t.Errorf("invalidating a missing key failed: %v", err)
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+	var userRequests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users" {
+			atomic.AddInt32(&userRequests, 1)
+			w.Write([]byte(testUsers))
+		}
+	})
+	mb1, server := newTestServer(t, JellyfinServer, handler)
+	mb2, err := NewServer(JellyfinServer, server.URL, "mediabrowser-test", "v0.0.0", "test", "test-id-2", nil, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mb := range []*MediaBrowser{mb1, mb2} {
+		mb.Authenticated = true
+		mb.SetCache(cache)
+		if users, err := mb.GetUsers(false); err != nil || len(users) != 2 {
+			t.Fatalf("GetUsers: %+v, %v", users, err)
+		}
+	}
+	if n := atomic.LoadInt32(&userRequests); n != 1 {
+		t.Fatalf("expected one request for both instances, got %d", n)
+	}
+
+	// Expiring the local copy by hand should still go to the server, rather than reloading what we stored.
+	mb1.CacheExpiry = time.Now()
+	mb1.GetUsers(false)
+	if n := atomic.LoadInt32(&userRequests); n != 2 {
+		t.Errorf("expected a forced reload to query the server, got %d requests", n)
+	}
+	// mb2 picks up the result from mb1.
+	mb2.CacheExpiry = time.Now()
+	mb2.GetUsers(false)
+	if n := atomic.LoadInt32(&userRequests); n != 2 {
+		t.Errorf("expected mb2 to use mb1's result, got %d requests", n)
+	}
+}
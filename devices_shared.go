@@ -0,0 +1,103 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+type devicesResult struct {
+	Items            []DeviceInfo `json:"Items"`
+	TotalRecordCount int          `json:"TotalRecordCount"`
+}
+
+// GetDevices returns the devices the given user has used. If userID is blank, all devices are returned.
+func (mb *MediaBrowser) GetDevices(userID string) ([]DeviceInfo, error) {
+	return mb.GetDevicesContext(context.Background(), userID)
+}
+
+// GetDevicesContext is GetDevices with a context controlling the request.
+func (mb *MediaBrowser) GetDevicesContext(ctx context.Context, userID string) ([]DeviceInfo, error) {
+	u := fmt.Sprintf("%s/Devices", mb.Server)
+	if userID != "" {
+		u += "?userId=" + url.QueryEscape(userID)
+	}
+	var result devicesResult
+	data, status, err := mb.getJSON(ctx, u, nil, &result, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetDeviceInfo returns the device corresponding to the given ID.
+func (mb *MediaBrowser) GetDeviceInfo(deviceID string) (DeviceInfo, error) {
+	return mb.GetDeviceInfoContext(context.Background(), deviceID)
+}
+
+// GetDeviceInfoContext is GetDeviceInfo with a context controlling the request.
+func (mb *MediaBrowser) GetDeviceInfoContext(ctx context.Context, deviceID string) (DeviceInfo, error) {
+	u := fmt.Sprintf("%s/Devices/Info?id=%s", mb.Server, url.QueryEscape(deviceID))
+	var device DeviceInfo
+	data, status, err := mb.getJSON(ctx, u, nil, &device, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	return device, nil
+}
+
+// DeleteDevice deletes the device corresponding to the given ID, logging out any sessions on it.
+func (mb *MediaBrowser) DeleteDevice(deviceID string) error {
+	return mb.DeleteDeviceContext(context.Background(), deviceID)
+}
+
+// DeleteDeviceContext is DeleteDevice with a context controlling the request.
+func (mb *MediaBrowser) DeleteDeviceContext(ctx context.Context, deviceID string) error {
+	u := fmt.Sprintf("%s/Devices?id=%s", mb.Server, url.QueryEscape(deviceID))
+	data, status, err := mb.delete(ctx, u)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
+
+// GetDeviceOptions returns the customizable options (i.e. the custom name) of the device corresponding to the given ID.
+func (mb *MediaBrowser) GetDeviceOptions(deviceID string) (DeviceOptions, error) {
+	return mb.GetDeviceOptionsContext(context.Background(), deviceID)
+}
+
+// GetDeviceOptionsContext is GetDeviceOptions with a context controlling the request.
+func (mb *MediaBrowser) GetDeviceOptionsContext(ctx context.Context, deviceID string) (DeviceOptions, error) {
+	u := fmt.Sprintf("%s/Devices/Options?id=%s", mb.Server, url.QueryEscape(deviceID))
+	var options DeviceOptions
+	data, status, err := mb.getJSON(ctx, u, nil, &options, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return DeviceOptions{}, err
+	}
+	return options, nil
+}
+
+// SetDeviceOptions sets the customizable options of the device corresponding to the given ID.
+// Setting a blank CustomName reverts to the name reported by the device.
+func (mb *MediaBrowser) SetDeviceOptions(deviceID string, options DeviceOptions) error {
+	return mb.SetDeviceOptionsContext(context.Background(), deviceID, options)
+}
+
+// SetDeviceOptionsContext is SetDeviceOptions with a context controlling the request.
+func (mb *MediaBrowser) SetDeviceOptionsContext(ctx context.Context, deviceID string, options DeviceOptions) error {
+	u := fmt.Sprintf("%s/Devices/Options?id=%s", mb.Server, url.QueryEscape(deviceID))
+	_, status, err := mb.post(ctx, u, options, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
@@ -59,6 +59,20 @@ func (err ErrUnauthorized) Error() string {
 	return msg
 }
 
+// ErrAPIKeyRevoked is returned in place of ErrUnauthorized when authenticated with an API key,
+// as the key can't be renewed like a username & password login can.
+type ErrAPIKeyRevoked struct {
+	DetailedError
+}
+
+func (err ErrAPIKeyRevoked) Error() string {
+	msg := "Unauthorized, the API key is invalid or has been revoked."
+	if err.IsVerbose() {
+		msg += " (" + err.Details() + ")"
+	}
+	return msg
+}
+
 type ErrForbidden struct {
 	DetailedError
 }
@@ -75,6 +89,34 @@ var (
 	NotFound ErrNotFound = errors.New("resource not found")
 )
 
+// ErrServerUnreachable is returned when no response could be got from the server,
+// e.g. the connection was refused or the DNS lookup failed.
+type ErrServerUnreachable struct {
+	Err error
+}
+
+func (err ErrServerUnreachable) Error() string {
+	return "server unreachable: " + err.Err.Error()
+}
+
+func (err ErrServerUnreachable) Unwrap() error {
+	return err.Err
+}
+
+// ErrTimeout is returned when a request to the server timed out, either through
+// the http.Client's timeout or a context deadline.
+type ErrTimeout struct {
+	Err error
+}
+
+func (err ErrTimeout) Error() string {
+	return "request timed out: " + err.Err.Error()
+}
+
+func (err ErrTimeout) Unwrap() error {
+	return err.Err
+}
+
 type ErrUnknown struct {
 	DetailedError
 }
@@ -93,6 +135,16 @@ func (mb *MediaBrowser) genericErr(status int, data string) (err error) {
 		err = nil
 		return
 	case 401, 400:
+		if status == 401 && mb.usingAPIKey() {
+			err = ErrAPIKeyRevoked{
+				DetailedError: DetailedError{
+					Data:    data,
+					Verbose: mb.Verbose,
+					Code:    status,
+				},
+			}
+			return
+		}
 		err = ErrUnauthorized{
 			DetailedError: DetailedError{
 				Data:    data,
@@ -144,6 +196,28 @@ func (err ErrUserNotFound) Error() string {
 	return msg
 }
 
+// ErrLibraryNotFound is returned when no library matches the given name or ID.
+type ErrLibraryNotFound struct {
+	name, id string
+}
+
+func (err ErrLibraryNotFound) Error() string {
+	if err.name != "" {
+		return "Library \"" + err.name + "\" not found."
+	}
+	return "Library with ID \"" + err.id + "\" not found."
+}
+
+// ErrPatchConflict is returned by PatchPolicy & PatchConfiguration when the value kept being changed by someone else while patching.
+type ErrPatchConflict struct {
+	userID   string
+	attempts int
+}
+
+func (err ErrPatchConflict) Error() string {
+	return fmt.Sprintf("User with ID \"%s\" was modified concurrently, gave up after %d attempts.", err.userID, err.attempts)
+}
+
 type ErrNoPolicySupplied struct {
 	DetailedError
 }
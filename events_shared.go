@@ -0,0 +1,403 @@
+package mediabrowser
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the "MessageType" of a message sent over the server's WebSocket.
+type EventType string
+
+const (
+	EventUserUpdated              EventType = "UserUpdated"
+	EventUserDeleted              EventType = "UserDeleted"
+	EventUserPolicyUpdated        EventType = "UserPolicyUpdated"
+	EventUserConfigurationUpdated EventType = "UserConfigurationUpdated"
+	EventSessions                 EventType = "Sessions" // Requires EventSocketOptions.SessionsInterval.
+	EventLibraryChanged           EventType = "LibraryChanged"
+	EventScheduledTaskEnded       EventType = "ScheduledTaskEnded"
+	EventActivityLogEntry         EventType = "ActivityLogEntry" // Requires EventSocketOptions.ActivityLog.
+
+	eventForceKeepAlive   EventType = "ForceKeepAlive"
+	eventKeepAlive        EventType = "KeepAlive"
+	eventSessionsStart    EventType = "SessionsStart"
+	eventActivityLogStart EventType = "ActivityLogEntryStart"
+)
+
+// errTokenRenewed is returned by runOnce when the handshake was refused because the token had expired, and a new one has been obtained.
+var errTokenRenewed = errors.New("token renewed")
+
+// Used until the server tells us otherwise with a ForceKeepAlive message.
+const defaultKeepAliveTimeout = 60 * time.Second
+
+// Event is a message received from the server. Depending on Type, one of the typed fields will be populated.
+// If the data couldn't be decoded, only Data is set.
+type Event struct {
+	Type      EventType
+	MessageID string
+	Data      json.RawMessage
+	// Set for UserUpdated, UserPolicyUpdated & UserConfigurationUpdated.
+	User *User
+	// Set for UserDeleted, and the above.
+	UserID string
+	// Set for Sessions.
+	Sessions []SessionInfo
+	// Set for LibraryChanged.
+	LibraryChange *LibraryUpdateInfo
+	// Set for ScheduledTaskEnded.
+	TaskResult *TaskResult
+	// Set for ActivityLogEntry.
+	ActivityLog []ActivityLogEntry
+}
+
+// EventSocketOptions configures an EventSocket.
+type EventSocketOptions struct {
+	SessionsInterval time.Duration // If non-zero, the server will send a "Sessions" event at this interval.
+	ActivityLog      bool          // If true, the server will send "ActivityLogEntry" events.
+	MinBackoff       time.Duration // Delay before the first reconnection attempt, doubled after each failure. Defaults to 1s.
+	MaxBackoff       time.Duration // Maximum delay between reconnection attempts. Defaults to 1m.
+}
+
+type socketMessage struct {
+	MessageType EventType       `json:"MessageType"`
+	MessageID   string          `json:"MessageId,omitempty"`
+	Data        json.RawMessage `json:"Data,omitempty"`
+}
+
+// EventSocket is a client for the server's WebSocket, delivering events to registered handlers.
+// Handlers are called one at a time, in the order events arrive, from the goroutine calling Run.
+type EventSocket struct {
+	mb           *MediaBrowser
+	opts         EventSocketOptions
+	handlerLock  sync.RWMutex
+	handlers     map[EventType][]func(Event)
+	anyHandlers  []func(Event)
+	onConnect    []func()
+	onDisconnect []func(error)
+	onError      []func(error)
+	connLock     sync.Mutex
+	conn         *wsConn
+}
+
+// NewEventSocket returns an EventSocket for this server. Register handlers, then call Run to connect.
+func (mb *MediaBrowser) NewEventSocket(opts EventSocketOptions) *EventSocket {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+	return &EventSocket{
+		mb:       mb,
+		opts:     opts,
+		handlers: map[EventType][]func(Event){},
+	}
+}
+
+// On registers a handler for events of the given type.
+func (s *EventSocket) On(eventType EventType, handler func(Event)) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+	s.handlers[eventType] = append(s.handlers[eventType], handler)
+}
+
+// OnAny registers a handler for every event.
+func (s *EventSocket) OnAny(handler func(Event)) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+	s.anyHandlers = append(s.anyHandlers, handler)
+}
+
+// OnConnect registers a handler called every time a connection is established.
+func (s *EventSocket) OnConnect(handler func()) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+	s.onConnect = append(s.onConnect, handler)
+}
+
+// OnDisconnect registers a handler called every time a connection is lost, with the reason.
+func (s *EventSocket) OnDisconnect(handler func(error)) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+	s.onDisconnect = append(s.onDisconnect, handler)
+}
+
+// OnError registers a handler called every time a connection attempt fails, with the reason.
+func (s *EventSocket) OnError(handler func(error)) {
+	s.handlerLock.Lock()
+	defer s.handlerLock.Unlock()
+	s.onError = append(s.onError, handler)
+}
+
+// Connected returns whether the socket is currently connected.
+func (s *EventSocket) Connected() bool {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	return s.conn != nil
+}
+
+// Run connects to the server and delivers events until ctx is cancelled, reconnecting with exponential backoff if the connection fails or drops.
+// Failed connection attempts are passed to OnError handlers. If the server rejects the credentials (ErrUnauthorized or ErrAPIKeyRevoked),
+// retrying won't help, so Run returns that error. Otherwise it returns ctx.Err() once cancelled.
+func (s *EventSocket) Run(ctx context.Context) error {
+	backoff := s.opts.MinBackoff
+	renewed := false
+	for {
+		connected, err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == errTokenRenewed {
+			// Retry straight away with the new token, though only once in a row in case the server keeps refusing it.
+			if !renewed {
+				renewed = true
+				continue
+			}
+		} else {
+			renewed = false
+		}
+		if connected {
+			backoff = s.opts.MinBackoff
+		} else if err != errTokenRenewed {
+			s.handlerLock.RLock()
+			onError := s.onError
+			s.handlerLock.RUnlock()
+			for _, handler := range onError {
+				handler(err)
+			}
+			switch err.(type) {
+			case ErrUnauthorized, ErrAPIKeyRevoked:
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+	}
+}
+
+// socketURL returns the address of the server's WebSocket. The token is passed as api_key, as headers aren't always passed through by reverse proxies.
+func (s *EventSocket) socketURL() (*url.URL, error) {
+	u, err := url.Parse(s.mb.Server)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	path := "/socket"
+	if s.mb.serverType == EmbyServer {
+		path = "/embywebsocket"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	token, _ := s.mb.credentials()
+	query := url.Values{}
+	query.Set("api_key", token)
+	query.Set("deviceId", s.mb.deviceID)
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// runOnce connects and serves a single connection, returning whether the connection was made and why it ended.
+func (s *EventSocket) runOnce(ctx context.Context) (connected bool, err error) {
+	if err = s.mb.ensureAuthenticated(ctx); err != nil {
+		return
+	}
+	u, err := s.socketURL()
+	if err != nil {
+		return
+	}
+	header := http.Header{}
+	s.mb.authLock.RLock()
+	for name, value := range s.mb.header {
+		header.Set(name, value)
+	}
+	s.mb.authLock.RUnlock()
+	var tlsConfig *tls.Config
+	if t, ok := s.mb.httpClient.Transport.(*http.Transport); ok {
+		tlsConfig = t.TLSClientConfig
+	}
+	conn, err := dialWebSocket(ctx, u, header, tlsConfig)
+	if handshakeErr, ok := err.(wsHandshakeError); ok {
+		if handshakeErr.status == 401 && s.mb.reauthenticate(ctx, header.Get("Authorization")) {
+			err = errTokenRenewed
+			return
+		}
+		if customErr := s.mb.genericErr(handshakeErr.status, handshakeErr.data); customErr != nil {
+			err = customErr
+		}
+	}
+	if err != nil {
+		return
+	}
+	connected = true
+	s.connLock.Lock()
+	s.conn = conn
+	s.connLock.Unlock()
+
+	done := make(chan struct{})
+	keepAlive := make(chan time.Duration, 1)
+	go func() {
+		// Close the connection on cancellation, which unblocks ReadMessage.
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	go s.keepAlive(conn, keepAlive, done)
+
+	s.handlerLock.RLock()
+	onConnect := s.onConnect
+	s.handlerLock.RUnlock()
+	for _, handler := range onConnect {
+		handler()
+	}
+
+	err = s.subscribe(conn)
+	for err == nil {
+		var data []byte
+		_, data, err = conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var msg socketMessage
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+		switch msg.MessageType {
+		case eventForceKeepAlive:
+			var seconds int
+			if json.Unmarshal(msg.Data, &seconds) == nil && seconds > 0 {
+				select {
+				case keepAlive <- time.Duration(seconds) * time.Second:
+				default:
+				}
+			}
+		case eventKeepAlive:
+		default:
+			s.dispatch(decodeEvent(msg))
+		}
+	}
+	close(done)
+	conn.Close()
+	s.connLock.Lock()
+	s.conn = nil
+	s.connLock.Unlock()
+
+	s.handlerLock.RLock()
+	onDisconnect := s.onDisconnect
+	s.handlerLock.RUnlock()
+	for _, handler := range onDisconnect {
+		handler(err)
+	}
+	return
+}
+
+// keepAlive sends a KeepAlive message at half the timeout given by the server's ForceKeepAlive message.
+// If sending fails, the connection is closed so the reader notices.
+func (s *EventSocket) keepAlive(conn *wsConn, timeouts chan time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(defaultKeepAliveTimeout / 2)
+	defer ticker.Stop()
+	msg, _ := json.Marshal(socketMessage{MessageType: eventKeepAlive})
+	for {
+		select {
+		case <-done:
+			return
+		case timeout := <-timeouts:
+			ticker.Stop()
+			ticker = time.NewTicker(timeout / 2)
+			if err := conn.WriteMessage(wsText, msg); err != nil {
+				conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteMessage(wsText, msg); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// subscribe asks the server for the periodic events requested in the options.
+func (s *EventSocket) subscribe(conn *wsConn) error {
+	var msgs []socketMessage
+	if s.opts.SessionsInterval > 0 {
+		data, _ := json.Marshal(fmt.Sprintf("0,%d", s.opts.SessionsInterval.Milliseconds()))
+		msgs = append(msgs, socketMessage{MessageType: eventSessionsStart, Data: data})
+	}
+	if s.opts.ActivityLog {
+		data, _ := json.Marshal("0,1000")
+		msgs = append(msgs, socketMessage{MessageType: eventActivityLogStart, Data: data})
+	}
+	for _, msg := range msgs {
+		data, _ := json.Marshal(msg)
+		if err := conn.WriteMessage(wsText, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeEvent(msg socketMessage) Event {
+	event := Event{Type: msg.MessageType, MessageID: msg.MessageID, Data: msg.Data}
+	switch msg.MessageType {
+	case EventUserUpdated, EventUserPolicyUpdated, EventUserConfigurationUpdated:
+		var user User
+		if json.Unmarshal(msg.Data, &user) == nil {
+			event.User = &user
+			event.UserID = user.ID
+		}
+	case EventUserDeleted:
+		json.Unmarshal(msg.Data, &event.UserID)
+	case EventSessions:
+		json.Unmarshal(msg.Data, &event.Sessions)
+	case EventLibraryChanged:
+		var info LibraryUpdateInfo
+		if json.Unmarshal(msg.Data, &info) == nil {
+			event.LibraryChange = &info
+		}
+	case EventScheduledTaskEnded:
+		var result TaskResult
+		if json.Unmarshal(msg.Data, &result) == nil {
+			event.TaskResult = &result
+		}
+	case EventActivityLogEntry:
+		json.Unmarshal(msg.Data, &event.ActivityLog)
+	}
+	return event
+}
+
+func (s *EventSocket) dispatch(event Event) {
+	s.handlerLock.RLock()
+	handlers := s.handlers[event.Type]
+	anyHandlers := s.anyHandlers
+	s.handlerLock.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+	for _, handler := range anyHandlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,425 @@
+package mediabrowser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acceptWebSocket is a bare-bones server side of the handshake, for standing in for the server's socket.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	conn, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newWSConn(conn, brw.Reader, false), nil
+}
+
+func sendSocketMessage(t *testing.T, conn *wsConn, msgType EventType, data string) {
+	msg, _ := json.Marshal(socketMessage{MessageType: msgType, Data: json.RawMessage(data)})
+	if err := conn.WriteMessage(wsText, msg); err != nil {
+		t.Errorf("failed to send %s: %v", msgType, err)
+	}
+}
+
+func TestEventSocket(t *testing.T) {
+	var lock sync.Mutex
+	var received []socketMessage
+	connections := 0
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/socket" {
+			return
+		}
+		if r.URL.Query().Get("api_key") != "key" {
+			w.WriteHeader(401)
+			return
+		}
+		conn, err := acceptWebSocket(w, r)
+		if err != nil {
+			t.Errorf("failed to accept: %v", err)
+			return
+		}
+		defer conn.Close()
+		lock.Lock()
+		connections++
+		first := connections == 1
+		lock.Unlock()
+		sendSocketMessage(t, conn, eventForceKeepAlive, `60`)
+		// Wait for the client's KeepAlive and SessionsStart.
+		for i := 0; i < 2; i++ {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Errorf("failed to read: %v", err)
+				return
+			}
+			var msg socketMessage
+			json.Unmarshal(data, &msg)
+			lock.Lock()
+			received = append(received, msg)
+			lock.Unlock()
+		}
+		if first {
+			sendSocketMessage(t, conn, EventUserDeleted, `"abcdef123456"`)
+			sendSocketMessage(t, conn, EventLibraryChanged, `{"ItemsAdded":["i1","i2"]}`)
+			// Drop the connection to check we reconnect.
+			return
+		}
+		sendSocketMessage(t, conn, EventUserUpdated, `{"Id":"abcdef123456","Name":"john"}`)
+		conn.ReadMessage()
+	}))
+	mb.SetAPIKey("key")
+
+	socket := mb.NewEventSocket(EventSocketOptions{
+		SessionsInterval: 1500 * time.Millisecond,
+		MinBackoff:       10 * time.Millisecond,
+	})
+	events := make(chan Event, 10)
+	socket.On(EventUserDeleted, func(e Event) { events <- e })
+	socket.On(EventUserUpdated, func(e Event) { events <- e })
+	socket.On(EventLibraryChanged, func(e Event) { events <- e })
+	disconnects := 0
+	socket.OnDisconnect(func(error) { disconnects++ })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	runErr := make(chan error)
+	go func() { runErr <- socket.Run(ctx) }()
+
+	expect := func(eventType EventType) Event {
+		select {
+		case e := <-events:
+			if e.Type != eventType {
+				t.Fatalf("got %s event, expected %s", e.Type, eventType)
+			}
+			return e
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s event", eventType)
+		}
+		return Event{}
+	}
+	if e := expect(EventUserDeleted); e.UserID != "abcdef123456" {
+		t.Errorf("UserDeleted decoded incorrectly: %+v", e)
+	}
+	if e := expect(EventLibraryChanged); e.LibraryChange == nil || len(e.LibraryChange.ItemsAdded) != 2 {
+		t.Errorf("LibraryChanged decoded incorrectly: %+v", e)
+	}
+	if e := expect(EventUserUpdated); e.User == nil || e.User.Name != "john" {
+		t.Errorf("UserUpdated decoded incorrectly: %+v", e)
+	}
+	cancel()
+	if err := <-runErr; err != context.Canceled {
+		t.Errorf("Run returned %v, expected context.Canceled", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if connections != 2 || disconnects != 2 {
+		t.Errorf("got %d connections and %d disconnects, expected 2 of each", connections, disconnects)
+	}
+	types := map[EventType]int{}
+	for _, msg := range received {
+		types[msg.MessageType]++
+		if msg.MessageType == eventSessionsStart && string(msg.Data) != `"0,1500"` {
+			t.Errorf("SessionsStart sent with data %s", msg.Data)
+		}
+	}
+	if types[eventKeepAlive] != 2 || types[eventSessionsStart] != 2 {
+		t.Errorf("client sent unexpected messages: %v", types)
+	}
+}
+
+func TestEventSocketErrors(t *testing.T) {
+	var lock sync.Mutex
+	attempts := 0
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/socket" {
+			return
+		}
+		lock.Lock()
+		attempts++
+		attempt := attempts
+		lock.Unlock()
+		// Fail once in a way worth retrying, then reject the key.
+		if attempt == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(401)
+	}))
+	mb.SetAPIKey("key")
+
+	socket := mb.NewEventSocket(EventSocketOptions{MinBackoff: 10 * time.Millisecond})
+	var failures []error
+	socket.OnError(func(err error) { failures = append(failures, err) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := socket.Run(ctx)
+	var revoked ErrAPIKeyRevoked
+	if !errors.As(err, &revoked) {
+		t.Fatalf("Run returned %T: %v, expected ErrAPIKeyRevoked", err, err)
+	}
+	var unknown ErrUnknown
+	if len(failures) != 2 || !errors.As(failures[0], &unknown) || !errors.As(failures[1], &revoked) {
+		t.Errorf("OnError got %v, expected ErrUnknown then ErrAPIKeyRevoked", failures)
+	}
+	for _, err := range failures {
+		if err == nil {
+			t.Error("OnError called with a nil error")
+		}
+	}
+
+	// An expired token is renewed and the connection retried straight away, without reporting an error.
+	logins := 0
+	mb, _ = newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Users/authenticatebyname":
+			lock.Lock()
+			logins++
+			token := fmt.Sprintf("token%d", logins)
+			lock.Unlock()
+			w.Write([]byte(`{"User":{"Id":"a1b2c3d4e5f6","Name":"admin"},"AccessToken":"` + token + `"}`))
+		case "/socket":
+			if r.URL.Query().Get("api_key") != "token2" {
+				w.WriteHeader(401)
+				return
+			}
+			conn, err := acceptWebSocket(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.ReadMessage()
+		}
+	}))
+	if _, err := mb.Authenticate("admin", "password"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	socket = mb.NewEventSocket(EventSocketOptions{MinBackoff: time.Minute})
+	failures = nil
+	socket.OnError(func(err error) { failures = append(failures, err) })
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	socket.OnConnect(cancel)
+	if err := socket.Run(ctx); err != context.Canceled {
+		t.Errorf("Run returned %v, expected to connect with the renewed token", err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("OnError got %v, expected nothing", failures)
+	}
+}
+
+func TestLiveUserCache(t *testing.T) {
+	var lock sync.Mutex
+	userRequests := 0
+	proceed := make(chan struct{})
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			lock.Lock()
+			userRequests++
+			lock.Unlock()
+			w.Write([]byte(`[{"Id":"aaaaaaaaaaaa","Name":"alice"},{"Id":"bbbbbbbbbbbb","Name":"bob"}]`))
+		case "/socket":
+			conn, err := acceptWebSocket(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			<-proceed
+			sendSocketMessage(t, conn, EventUserDeleted, `"aaaaaaaaaaaa"`)
+			sendSocketMessage(t, conn, EventUserPolicyUpdated, `{"Id":"bbbbbbbbbbbb","Name":"Robert","Policy":{"IsAdministrator":true}}`)
+			sendSocketMessage(t, conn, EventUserUpdated, `{"Id":"cccccccccccc","Name":"carol"}`)
+			conn.ReadMessage()
+		}
+	}))
+	mb.SetAPIKey("key")
+
+	socket := mb.NewEventSocket(EventSocketOptions{})
+	mb.EnableLiveUserCache(socket)
+	connected := make(chan struct{}, 1)
+	socket.OnConnect(func() { connected <- struct{}{} })
+	handled := make(chan struct{}, 3)
+	for _, eventType := range []EventType{EventUserDeleted, EventUserPolicyUpdated, EventUserUpdated} {
+		socket.On(eventType, func(Event) { handled <- struct{}{} })
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go socket.Run(ctx)
+	<-connected
+	// The cache is loaded on connection, and shouldn't expire while connected.
+	mb.cacheLength = 0
+	if _, err := mb.UserByName("alice", false); err != nil {
+		t.Fatalf("UserByName failed: %v", err)
+	}
+	close(proceed)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-handled:
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for events")
+		}
+	}
+
+	if _, err := mb.UserByIDFromCache("aaaaaaaaaaaa"); err == nil {
+		t.Error("deleted user still in cache")
+	}
+	if u, err := mb.UserByNameFromCache("robert"); err != nil || !u.Policy.IsAdministrator {
+		t.Errorf("updated user not patched into cache: %+v, %v", u, err)
+	}
+	if _, err := mb.UserByNameFromCache("bob"); err == nil {
+		t.Error("renamed user still findable by old name")
+	}
+	if _, err := mb.UserByIDFromCache("cccccccccccc"); err != nil {
+		t.Errorf("new user not added to cache: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if userRequests != 1 {
+		t.Errorf("user list was requested %d times, expected once", userRequests)
+	}
+}
+
+func TestLiveUserCacheReconnect(t *testing.T) {
+	var lock sync.Mutex
+	deleted := false
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			lock.Lock()
+			defer lock.Unlock()
+			if deleted {
+				w.Write([]byte(`[{"Id":"aaaaaaaaaaaa","Name":"alice"}]`))
+				return
+			}
+			w.Write([]byte(`[{"Id":"aaaaaaaaaaaa","Name":"alice"},{"Id":"bbbbbbbbbbbb","Name":"bob"}]`))
+		case "/socket":
+			conn, err := acceptWebSocket(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			lock.Lock()
+			deleted = true
+			lock.Unlock()
+			sendSocketMessage(t, conn, EventUserDeleted, `"bbbbbbbbbbbb"`)
+			conn.ReadMessage()
+		}
+	}))
+	mb.SetAPIKey("key")
+	// Fill the cache before connecting, so connecting has something to clear.
+	if _, err := mb.GetUsers(false); err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+
+	socket := mb.NewEventSocket(EventSocketOptions{})
+	mb.EnableLiveUserCache(socket)
+	handled := make(chan struct{}, 1)
+	socket.On(EventUserDeleted, func(Event) { handled <- struct{}{} })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go socket.Run(ctx)
+	select {
+	case <-handled:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for UserDeleted")
+	}
+
+	if _, err := mb.UserByIDFromCache("bbbbbbbbbbbb"); err == nil {
+		t.Error("deleted user still in cache")
+	}
+	if _, err := mb.UserByNameFromCache("alice"); err != nil {
+		t.Errorf("UserByNameFromCache failed: %v", err)
+	}
+}
+
+func TestLiveUserCacheEventDuringRefresh(t *testing.T) {
+	var lock sync.Mutex
+	deleted := false
+	userRequests := 0
+	requested, release, proceed := make(chan struct{}, 1), make(chan struct{}), make(chan struct{})
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			lock.Lock()
+			userRequests++
+			first, wasDeleted := userRequests == 1, deleted
+			lock.Unlock()
+			if wasDeleted {
+				w.Write([]byte(`[{"Id":"aaaaaaaaaaaa","Name":"alice"}]`))
+				return
+			}
+			body := `[{"Id":"aaaaaaaaaaaa","Name":"alice"},{"Id":"bbbbbbbbbbbb","Name":"bob"}]`
+			if first {
+				// Hold the response, which is already out of date, until bob has been deleted.
+				requested <- struct{}{}
+				<-release
+			}
+			w.Write([]byte(body))
+		case "/socket":
+			conn, err := acceptWebSocket(w, r)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			<-proceed
+			lock.Lock()
+			deleted = true
+			lock.Unlock()
+			sendSocketMessage(t, conn, EventUserDeleted, `"bbbbbbbbbbbb"`)
+			conn.ReadMessage()
+		}
+	}))
+	mb.SetAPIKey("key")
+
+	socket := mb.NewEventSocket(EventSocketOptions{})
+	mb.EnableLiveUserCache(socket)
+	connected := make(chan struct{}, 1)
+	socket.OnConnect(func() { connected <- struct{}{} })
+	handled := make(chan struct{}, 1)
+	socket.On(EventUserDeleted, func(Event) { handled <- struct{}{} })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go socket.Run(ctx)
+	<-connected
+
+	result := make(chan []User)
+	go func() {
+		users, err := mb.GetUsers(false)
+		if err != nil {
+			t.Errorf("GetUsers failed: %v", err)
+		}
+		result <- users
+	}()
+	<-requested
+	close(proceed)
+	select {
+	case <-handled:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for UserDeleted")
+	}
+	close(release)
+
+	if users := <-result; len(users) != 1 {
+		t.Errorf("GetUsers returned %+v, expected the deleted user to be gone", users)
+	}
+	if _, err := mb.UserByIDFromCache("bbbbbbbbbbbb"); err == nil {
+		t.Error("deleted user back in cache")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if userRequests != 2 {
+		t.Errorf("user list was requested %d times, expected it to be reloaded once", userRequests)
+	}
+}
@@ -0,0 +1,127 @@
+package mediabrowser
+
+// Policy and Configuration keep any properties we don't model in Extra, so a read-modify-write cycle on a newer server doesn't reset them.
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Lower-case JSON names of each struct's fields, by type.
+var knownFields sync.Map
+
+func knownFieldsOf(t reflect.Type) map[string]bool {
+	if known, ok := knownFields.Load(t); ok {
+		return known.(map[string]bool)
+	}
+	known := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Name
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+			name = tagName
+		}
+		// encoding/json matches names case-insensitively, so we do too.
+		known[strings.ToLower(name)] = true
+	}
+	knownFields.Store(t, known)
+	return known
+}
+
+// unknownFields returns the properties of the JSON object in data that don't correspond to a field of t.
+func unknownFields(data []byte, t reflect.Type) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	known := knownFieldsOf(t)
+	var extra map[string]json.RawMessage
+	for key, value := range all {
+		if known[strings.ToLower(key)] {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// marshalWithExtra marshals v (which should encode to an object), appending the properties in extra in key order.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	known := knownFieldsOf(reflect.TypeOf(v))
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		// Anything we model takes precedence.
+		if !known[strings.ToLower(key)] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var out bytes.Buffer
+	out.Write(data[:len(data)-1])
+	empty := len(bytes.TrimSpace(data[1:len(data)-1])) == 0
+	for _, key := range keys {
+		if !empty {
+			out.WriteByte(',')
+		}
+		empty = false
+		name, _ := json.Marshal(key)
+		out.Write(name)
+		out.WriteByte(':')
+		if len(extra[key]) == 0 {
+			out.WriteString("null")
+		} else {
+			out.Write(extra[key])
+		}
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	type policy Policy
+	// Extra is filled even if a known field has the wrong type, so callers that keep the partial result don't lose it.
+	err := json.Unmarshal(data, (*policy)(p))
+	if extra, extraErr := unknownFields(data, reflect.TypeOf(policy{})); extraErr == nil {
+		p.Extra = extra
+	} else if err == nil {
+		err = extraErr
+	}
+	return err
+}
+
+func (p Policy) MarshalJSON() ([]byte, error) {
+	type policy Policy
+	return marshalWithExtra(policy(p), p.Extra)
+}
+
+func (c *Configuration) UnmarshalJSON(data []byte) error {
+	type configuration Configuration
+	// Extra is filled even if a known field has the wrong type, so callers that keep the partial result don't lose it.
+	err := json.Unmarshal(data, (*configuration)(c))
+	if extra, extraErr := unknownFields(data, reflect.TypeOf(configuration{})); extraErr == nil {
+		c.Extra = extra
+	} else if err == nil {
+		err = extraErr
+	}
+	return err
+}
+
+func (c Configuration) MarshalJSON() ([]byte, error) {
+	type configuration Configuration
+	return marshalWithExtra(configuration(c), c.Extra)
+}
@@ -0,0 +1,51 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPolicyRoundTrip(t *testing.T) {
+	in := `{"IsAdministrator":true,"EnableAllFolders":true,"SomeNewSetting":{"a":[1,2]},"AnotherOne":"x"}`
+	var p Policy
+	if err := json.Unmarshal([]byte(in), &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsAdministrator || len(p.Extra) != 2 {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	p.IsAdministrator = false
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output %s: %v", out, err)
+	}
+	if string(got["SomeNewSetting"]) != `{"a":[1,2]}` || string(got["AnotherOne"]) != `"x"` {
+		t.Errorf("unknown properties not preserved: %s", out)
+	}
+	if string(got["IsAdministrator"]) != "false" {
+		t.Errorf("modelled property not updated: %s", out)
+	}
+
+	// Known properties in Extra mustn't be duplicated.
+	c := Configuration{SubtitleMode: "Smart", Extra: map[string]json.RawMessage{"subtitlemode": []byte(`"None"`)}}
+	out, _ = json.Marshal(&c)
+	if strings.Count(strings.ToLower(string(out)), "subtitlemode") != 1 {
+		t.Errorf("duplicate property in output: %s", out)
+	}
+	// User embeds both, so should preserve them too.
+	var u User
+	json.Unmarshal([]byte(`{"Id":"aaaaaaaaaaaa","Policy":`+in+`,"Configuration":{"NewOption":1}}`), &u)
+	if len(u.Policy.Extra) != 2 || string(u.Configuration.Extra["NewOption"]) != "1" {
+		t.Errorf("unknown properties lost through User: %+v", u)
+	}
+	// A known property with the wrong type is an error, but unknown properties are still kept.
+	p = Policy{}
+	if err := json.Unmarshal([]byte(`{"IsHidden":"yes","AnotherOne":"x"}`), &p); err == nil || string(p.Extra["AnotherOne"]) != `"x"` {
+		t.Errorf("expected type error with Extra kept, got %v, %+v", err, p.Extra)
+	}
+}
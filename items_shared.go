@@ -0,0 +1,136 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SortOrder is the direction results are sorted in.
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "Ascending"
+	SortDescending SortOrder = "Descending"
+)
+
+// ItemFilter narrows down the items returned by GetItems.
+type ItemFilter string
+
+const (
+	FilterIsFolder          ItemFilter = "IsFolder"
+	FilterIsNotFolder       ItemFilter = "IsNotFolder"
+	FilterIsUnplayed        ItemFilter = "IsUnplayed"
+	FilterIsPlayed          ItemFilter = "IsPlayed"
+	FilterIsFavorite        ItemFilter = "IsFavorite"
+	FilterIsResumable       ItemFilter = "IsResumable"
+	FilterLikes             ItemFilter = "Likes"
+	FilterDislikes          ItemFilter = "Dislikes"
+	FilterIsFavoriteOrLikes ItemFilter = "IsFavoriteOrLikes"
+)
+
+// ItemsQuery describes which items GetItems returns. Zero values are left out of the request.
+type ItemsQuery struct {
+	// If set, items are fetched as seen by this user: only those they can access are returned, with their UserData.
+	// Filters like IsPlayed & IsFavorite need this.
+	UserID           string
+	ParentID         string
+	IDs              []string
+	IncludeItemTypes []string // e.g. "Movie", "Series", "Episode".
+	Recursive        bool
+	Fields           []string // Extra fields to include, e.g. "Tags", "Genres", "ProviderIds".
+	SortBy           []string // e.g. "SortName", "DateCreated", "ProductionYear".
+	SortOrder        SortOrder
+	Filters          []ItemFilter
+	Tags             []string
+	Genres           []string
+	Years            []int
+	SearchTerm       string
+	StartIndex       int
+	Limit            int // 0 means no limit.
+}
+
+// ItemsResult is a page of items returned by GetItems.
+type ItemsResult struct {
+	Items            []BaseItem `json:"Items"`
+	TotalRecordCount int        `json:"TotalRecordCount"`
+	StartIndex       int        `json:"StartIndex"`
+}
+
+// values returns the query string parameters for the query. Names are accepted case-insensitively by both servers.
+func (q ItemsQuery) values() url.Values {
+	v := url.Values{}
+	setList := func(key string, values []string, sep string) {
+		if len(values) != 0 {
+			v.Set(key, strings.Join(values, sep))
+		}
+	}
+	if q.ParentID != "" {
+		v.Set("parentId", q.ParentID)
+	}
+	setList("ids", q.IDs, ",")
+	setList("includeItemTypes", q.IncludeItemTypes, ",")
+	if q.Recursive {
+		v.Set("recursive", "true")
+	}
+	setList("fields", q.Fields, ",")
+	setList("sortBy", q.SortBy, ",")
+	if q.SortOrder != "" {
+		v.Set("sortOrder", string(q.SortOrder))
+	}
+	filters := make([]string, len(q.Filters))
+	for i, f := range q.Filters {
+		filters[i] = string(f)
+	}
+	setList("filters", filters, ",")
+	// Tags & genres can contain commas, so are pipe-delimited.
+	setList("tags", q.Tags, "|")
+	setList("genres", q.Genres, "|")
+	years := make([]string, len(q.Years))
+	for i, y := range q.Years {
+		years[i] = strconv.Itoa(y)
+	}
+	setList("years", years, ",")
+	if q.SearchTerm != "" {
+		v.Set("searchTerm", q.SearchTerm)
+	}
+	if q.StartIndex != 0 {
+		v.Set("startIndex", strconv.Itoa(q.StartIndex))
+	}
+	if q.Limit != 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+// itemsURL returns the URL for the given query, using /Users/{id}/Items if a user is given, and /Items otherwise.
+func (mb *MediaBrowser) itemsURL(query ItemsQuery) string {
+	u := fmt.Sprintf("%s/Items", mb.Server)
+	if query.UserID != "" {
+		u = fmt.Sprintf("%s/Users/%s/Items", mb.Server, query.UserID)
+	}
+	if values := query.values(); len(values) != 0 {
+		u += "?" + values.Encode()
+	}
+	return u
+}
+
+// GetItems returns the items matching the given query.
+func (mb *MediaBrowser) GetItems(query ItemsQuery) (ItemsResult, error) {
+	return mb.GetItemsContext(context.Background(), query)
+}
+
+// GetItemsContext is GetItems with a context controlling the request.
+func (mb *MediaBrowser) GetItemsContext(ctx context.Context, query ItemsQuery) (ItemsResult, error) {
+	var result ItemsResult
+	data, status, err := mb.getJSON(ctx, mb.itemsURL(query), nil, &result, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return ItemsResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package mediabrowser
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestGetItems(t *testing.T) {
+	var gotPath, gotQuery string
+	mb, _ := newTestServer(t, EmbyServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		w.Write([]byte(`{"Items":[{"Id":"1","Name":"Heat","Type":"Movie","RunTimeTicks":102000000000,"ProductionYear":1995,
+			"OfficialRating":"R","Tags":["Crime"],"ImageTags":{"Primary":"abc"},"ProviderIds":{"Imdb":"tt0113277"},
+			"UserData":{"PlaybackPositionTicks":0,"PlayCount":2,"IsFavorite":true,"Played":true,"LastPlayedDate":null}}],
+			"TotalRecordCount":40,"StartIndex":10}`))
+	}))
+	result, err := mb.GetItems(ItemsQuery{
+		UserID:           "aaaaaaaaaaaa",
+		IncludeItemTypes: []string{"Movie", "Series"},
+		Recursive:        true,
+		Fields:           []string{"Tags", "ProviderIds"},
+		SortBy:           []string{"SortName"},
+		SortOrder:        SortDescending,
+		Filters:          []ItemFilter{FilterIsPlayed},
+		Tags:             []string{"Crime", "Drama, Classic"},
+		Years:            []int{1995, 1996},
+		StartIndex:       10,
+		Limit:            1,
+	})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	if gotPath != "/Users/aaaaaaaaaaaa/Items" {
+		t.Errorf("unexpected path %s", gotPath)
+	}
+	const wantQuery = "fields=Tags%2CProviderIds&filters=IsPlayed&includeItemTypes=Movie%2CSeries&limit=1&recursive=true" +
+		"&sortBy=SortName&sortOrder=Descending&startIndex=10&tags=Crime%7CDrama%2C+Classic&years=1995%2C1996"
+	if gotQuery != wantQuery {
+		t.Errorf("unexpected query:\n got %s\nwant %s", gotQuery, wantQuery)
+	}
+	if result.TotalRecordCount != 40 || len(result.Items) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	item := result.Items[0]
+	if item.OfficialRating != "R" || item.Tags[0] != "Crime" || item.ImageTags["Primary"] != "abc" || item.ProviderIDs["Imdb"] != "tt0113277" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if item.UserData == nil || !item.UserData.Played || item.UserData.PlayCount != 2 {
+		t.Errorf("unexpected user data: %+v", item.UserData)
+	}
+
+	mb.GetItems(ItemsQuery{ParentID: "abc"})
+	if gotPath != "/Items" || gotQuery != "parentId=abc" {
+		t.Errorf("unexpected request %s?%s", gotPath, gotQuery)
+	}
+}
+
+func TestItemIterator(t *testing.T) {
+	const total = 250
+	var requests int
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			w.Write([]byte(testUsers))
+			return
+		case "/Items":
+		default:
+			return
+		}
+		requests++
+		q := r.URL.Query()
+		start, _ := strconv.Atoi(q.Get("startIndex"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		fmt.Fprintf(gz, `{"StartIndex":%d,"Extra":{"a":[1,{"b":2}]},"Items":[`, start)
+		for i := start; i < start+limit && i < total; i++ {
+			if i != start {
+				gz.Write([]byte(","))
+			}
+			fmt.Fprintf(gz, `{"Id":"%d","Name":"Item %d","Tags":["t"]}`, i, i)
+		}
+		fmt.Fprintf(gz, `],"TotalRecordCount":%d}`, total)
+	}))
+	mb.Authenticated = true
+
+	it := mb.ItemIterator(ItemsQuery{Recursive: true})
+	n := 0
+	for it.Next() {
+		if item := it.Item(); item.ID != strconv.Itoa(n) || len(item.Tags) != 1 {
+			t.Fatalf("unexpected item %d: %+v", n, item)
+		}
+		n++
+	}
+	if it.Err() != nil {
+		t.Fatalf("iteration failed: %v", it.Err())
+	}
+	if n != total || requests != 3 {
+		t.Errorf("got %d items in %d requests, want %d in 3", n, requests, total)
+	}
+
+	// Stopping early shouldn't make any more requests.
+	requests = 0
+	it = mb.ItemIterator(ItemsQuery{StartIndex: 240, Limit: 5})
+	it.Next()
+	it.Close()
+	if it.Next() || requests != 1 {
+		t.Errorf("iterator continued after Close (%d requests)", requests)
+	}
+
+	users := mb.UserIterator()
+	var names []string
+	for users.Next() {
+		names = append(names, users.Item().Name)
+	}
+	if users.Err() != nil || len(names) != 2 || names[1] != "Bob" {
+		t.Errorf("unexpected users %v: %v", names, users.Err())
+	}
+}
+
+func TestItemIteratorShortPages(t *testing.T) {
+	// The server caps pages at 50 items, and claims more than it has.
+	const total, claimed, maxPage = 120, 130, 50
+	var requests int
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Items" {
+			return
+		}
+		requests++
+		q := r.URL.Query()
+		start, _ := strconv.Atoi(q.Get("startIndex"))
+		items := ""
+		for i := start; i < start+maxPage && i < total; i++ {
+			if items != "" {
+				items += ","
+			}
+			items += fmt.Sprintf(`{"Id":"%d"}`, i)
+		}
+		fmt.Fprintf(w, `{"TotalRecordCount":%d,"Items":[%s]}`, claimed, items)
+	}))
+	mb.Authenticated = true
+
+	it := mb.ItemIterator(ItemsQuery{Limit: 100})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if it.Err() != nil {
+		t.Fatalf("iteration failed: %v", it.Err())
+	}
+	// 3 pages, then an empty one.
+	if n != total || requests != 4 {
+		t.Errorf("got %d items in %d requests, want %d in 4", n, requests, total)
+	}
+}
+
+func TestUserIteratorAuthentication(t *testing.T) {
+	logins := 0
+	var params map[string]string
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Users/authenticatebyname":
+			logins++
+			w.Write([]byte(`{"User":{"Id":"a1b2c3d4e5f6","Name":"admin"},"AccessToken":"token"}`))
+		case "/users":
+			json.NewDecoder(r.Body).Decode(&params)
+			w.Write([]byte(testUsers))
+		}
+	}))
+	if _, err := mb.Authenticate("admin", "password"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	mb.Authenticated = false
+
+	it := mb.UserIterator()
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if it.Err() != nil || n != 2 {
+		t.Fatalf("got %d users: %v", n, it.Err())
+	}
+	if logins != 2 {
+		t.Errorf("logged in %d times, expected the iterator to log in again", logins)
+	}
+	if params["Username"] != "admin" {
+		t.Errorf("login parameters not sent: %v", params)
+	}
+}
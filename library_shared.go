@@ -1,40 +1,77 @@
 package mediabrowser
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
 	"time"
 )
 
 // GetLibraries returns a list of the Libaries (called VirtualFolders) on this node.
 func (mb *MediaBrowser) GetLibraries() ([]VirtualFolder, int, error) {
+	return mb.GetLibrariesContext(context.Background())
+}
+
+// GetLibrariesContext is GetLibraries with a context controlling the request.
+func (mb *MediaBrowser) GetLibrariesContext(ctx context.Context) ([]VirtualFolder, int, error) {
 	var result []VirtualFolder
 	var data string
 	var status int
 	var err error
-	if time.Now().After(mb.LibraryCacheExpiry) {
+	mb.cacheLock.RLock()
+	expired, cache := time.Now().After(mb.LibraryCacheExpiry), mb.libraryCache
+	mb.cacheLock.RUnlock()
+	if expired {
+		key := mb.libraryCacheKey()
+		mb.cacheLock.RLock()
+		loaded := mb.libraryCacheEntry
+		mb.cacheLock.RUnlock()
+		if cached, expiry, ok := mb.cachedResponse(key, loaded); ok && json.Unmarshal(cached, &result) == nil {
+			mb.cacheLock.Lock()
+			mb.libraryCache = result
+			mb.LibraryCacheExpiry, mb.libraryCacheEntry = expiry, expiry
+			mb.cacheLock.Unlock()
+			return result, 200, nil
+		}
+		result = nil
 		url := fmt.Sprintf("%s/Library/VirtualFolders", mb.Server)
-		data, status, err = mb.get(url, nil)
-		if customErr := mb.genericErr(status, ""); customErr != nil {
+		raw := mb.responseBuffer()
+		data, status, err = mb.getJSON(ctx, url, nil, &result, raw)
+		if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 			err = customErr
 		}
 		if err != nil || status != 200 {
 			return nil, status, err
 		}
-		err := json.Unmarshal([]byte(data), &result)
-		if err != nil {
-			return nil, status, err
-		}
+		expiry := mb.storeResponse(key, raw)
+		mb.cacheLock.Lock()
 		mb.libraryCache = result
-		mb.LibraryCacheExpiry = time.Now().Add(time.Minute * time.Duration(mb.cacheLength))
+		mb.LibraryCacheExpiry, mb.libraryCacheEntry = expiry, expiry
+		mb.cacheLock.Unlock()
 		return result, status, nil
 	}
-	return mb.libraryCache, 200, nil
+	return cache, 200, nil
+}
+
+// expireLibraryCache forces the library cache to be reloaded on next use, after we've changed something.
+func (mb *MediaBrowser) expireLibraryCache() {
+	mb.cacheLock.Lock()
+	mb.LibraryCacheExpiry = time.Now()
+	cache := mb.cache
+	mb.cacheLock.Unlock()
+	if cache != nil {
+		cache.Invalidate(mb.libraryCacheKey())
+	}
 }
 
 // AddLibrary creates a library (VirtualFolder) for this node.
 func (mb *MediaBrowser) AddLibrary(name string, collectionType string, paths []string, refreshLibrary bool, LibraryOptions LibraryOptions) (int, error) {
+	return mb.AddLibraryContext(context.Background(), name, collectionType, paths, refreshLibrary, LibraryOptions)
+}
+
+// AddLibraryContext is AddLibrary with a context controlling the request.
+func (mb *MediaBrowser) AddLibraryContext(ctx context.Context, name string, collectionType string, paths []string, refreshLibrary bool, LibraryOptions LibraryOptions) (int, error) {
 	pathQuery := ""
 	for _, path := range paths {
 		// element is the element from someSlice for where we are
@@ -42,61 +79,224 @@ func (mb *MediaBrowser) AddLibrary(name string, collectionType string, paths []s
 	}
 	url := fmt.Sprintf("%s/Library/VirtualFolders?client=emby&name=%s&collectiontype=%s&refreshLibrary=%t%s", mb.Server, name, collectionType, refreshLibrary, pathQuery)
 	DeNullLibraryOptions(&LibraryOptions)
-	_, status, err := mb.post(url, LibraryOptions, false)
-	if customErr := mb.genericErr(status, ""); customErr != nil {
+	_, status, err := mb.post(ctx, url, LibraryOptions, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.expireLibraryCache()
+	}
 	return status, err
 }
 
 // DeleteLibrary deletes the library (VirtualFolder) corresponding to the provided name.
 func (mb *MediaBrowser) DeleteLibrary(name string) (int, error) {
+	return mb.DeleteLibraryContext(context.Background(), name)
+}
+
+// DeleteLibraryContext is DeleteLibrary with a context controlling the request.
+func (mb *MediaBrowser) DeleteLibraryContext(ctx context.Context, name string) (int, error) {
 	url := fmt.Sprintf("%s/Library/VirtualFolders?name=%s", mb.Server, name)
-	req, _ := http.NewRequest("DELETE", url, nil)
-	for name, value := range mb.header {
-		req.Header.Add(name, value)
-	}
-	resp, err := mb.httpClient.Do(req)
-	defer mb.timeoutHandler()
-	defer resp.Body.Close()
-	if customErr := mb.genericErr(resp.StatusCode, ""); customErr != nil {
+	_, status, err := mb.delete(ctx, url)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
-	return resp.StatusCode, err
+	if err == nil {
+		mb.expireLibraryCache()
+	}
+	return status, err
 }
 
 // AddFolder adds a subfolder to a library (VirtualFolder)
 func (mb *MediaBrowser) AddFolder(refreshLibrary bool, AddMedia AddMedia) (int, error) {
+	return mb.AddFolderContext(context.Background(), refreshLibrary, AddMedia)
+}
+
+// AddFolderContext is AddFolder with a context controlling the request.
+func (mb *MediaBrowser) AddFolderContext(ctx context.Context, refreshLibrary bool, AddMedia AddMedia) (int, error) {
 	url := fmt.Sprintf("%s/Library/VirtualFolders/Paths?client=emby&refreshLibrary=%t", mb.Server, refreshLibrary)
-	_, status, err := mb.post(url, AddMedia, false)
-	if customErr := mb.genericErr(status, ""); customErr != nil {
+	_, status, err := mb.post(ctx, url, AddMedia, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.expireLibraryCache()
+	}
 	return status, err
 }
 
 // DeleteFolder deletes the library (VirtualFolder) corresponding to the provided name.
 func (mb *MediaBrowser) DeleteFolder(name string, path string, refreshLibrary bool) (int, error) {
+	return mb.DeleteFolderContext(context.Background(), name, path, refreshLibrary)
+}
+
+// DeleteFolderContext is DeleteFolder with a context controlling the request.
+func (mb *MediaBrowser) DeleteFolderContext(ctx context.Context, name string, path string, refreshLibrary bool) (int, error) {
 	url := fmt.Sprintf("%s/Library/VirtualFolders/Paths?name=%s&path=%s&refreshLibrary=%t", mb.Server, name, path, refreshLibrary)
-	req, _ := http.NewRequest("DELETE", url, nil)
-	for name, value := range mb.header {
-		req.Header.Add(name, value)
-	}
-	resp, err := mb.httpClient.Do(req)
-	defer mb.timeoutHandler()
-	defer resp.Body.Close()
-	if customErr := mb.genericErr(resp.StatusCode, ""); customErr != nil {
+	_, status, err := mb.delete(ctx, url)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
-	return resp.StatusCode, err
+	if err == nil {
+		mb.expireLibraryCache()
+	}
+	return status, err
 }
 
 // ScanLibs triggers a scan of all libraries.
 func (mb *MediaBrowser) ScanLibs() (int, error) {
+	return mb.ScanLibsContext(context.Background())
+}
+
+// ScanLibsContext is ScanLibs with a context controlling the request.
+func (mb *MediaBrowser) ScanLibsContext(ctx context.Context) (int, error) {
 	url := fmt.Sprintf("%s/Library/Refresh?client=emby", mb.Server)
-	_, status, err := mb.post(url, nil, false)
-	if customErr := mb.genericErr(status, ""); customErr != nil {
+	_, status, err := mb.post(ctx, url, nil, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
 	return status, err
 }
+
+// normalizeID returns the given ID without hyphens and in lower case, for comparing IDs that may or may not be hyphenated.
+func normalizeID(id string) string {
+	return strings.ToLower(strings.ReplaceAll(id, "-", ""))
+}
+
+// formatID returns the given GUID hyphenated or not, matching what the server uses (see MediaBrowser.Hyphens).
+// Non-GUID IDs (like Emby's numeric ones) are returned unchanged.
+func (mb *MediaBrowser) formatID(id string) string {
+	plain := normalizeID(id)
+	if len(plain) != 32 {
+		return id
+	}
+	mb.cacheLock.RLock()
+	hyphens := mb.Hyphens
+	mb.cacheLock.RUnlock()
+	if !hyphens {
+		return plain
+	}
+	return plain[:8] + "-" + plain[8:12] + "-" + plain[12:16] + "-" + plain[16:20] + "-" + plain[20:]
+}
+
+// policyID returns the ID used for the library in a Policy's folder lists: on Emby its Guid (falling back to ItemId on servers without one),
+// and on Jellyfin its ItemId, hyphenated to match the server.
+func (mb *MediaBrowser) policyID(library VirtualFolder) string {
+	if mb.serverType == EmbyServer && library.Guid != "" {
+		return library.Guid
+	}
+	return mb.formatID(library.ItemId)
+}
+
+// libraryIDs returns the normalized forms of every ID the library can be referred to by (ItemId, and Guid on Emby).
+func libraryIDs(library VirtualFolder) []string {
+	ids := []string{}
+	for _, id := range []string{library.ItemId, library.Guid} {
+		if id != "" {
+			ids = append(ids, normalizeID(id))
+		}
+	}
+	return ids
+}
+
+// LibraryIDByName returns the ID of the library with the given name (case-insensitive), as used in Policy.EnabledFolders.
+// On Emby, this is the library's Guid rather than its ItemId.
+func (mb *MediaBrowser) LibraryIDByName(name string) (string, error) {
+	return mb.LibraryIDByNameContext(context.Background(), name)
+}
+
+// LibraryIDByNameContext is LibraryIDByName with a context controlling any library cache reload.
+func (mb *MediaBrowser) LibraryIDByNameContext(ctx context.Context, name string) (string, error) {
+	libraries, _, err := mb.GetLibrariesContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, library := range libraries {
+		if strings.EqualFold(library.Name, name) {
+			return mb.policyID(library), nil
+		}
+	}
+	return "", ErrLibraryNotFound{name: name}
+}
+
+// LibraryNameByID returns the name of the library with the given ID (ItemId, or Guid on Emby), which may or may not be hyphenated.
+func (mb *MediaBrowser) LibraryNameByID(libraryID string) (string, error) {
+	return mb.LibraryNameByIDContext(context.Background(), libraryID)
+}
+
+// LibraryNameByIDContext is LibraryNameByID with a context controlling any library cache reload.
+func (mb *MediaBrowser) LibraryNameByIDContext(ctx context.Context, libraryID string) (string, error) {
+	libraries, _, err := mb.GetLibrariesContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	id := normalizeID(libraryID)
+	for _, library := range libraries {
+		for _, other := range libraryIDs(library) {
+			if other == id {
+				return library.Name, nil
+			}
+		}
+	}
+	return "", ErrLibraryNotFound{id: libraryID}
+}
+
+// SetUserLibraries restricts the user to the libraries with the given names (case-insensitive), replacing any existing selection.
+// Enabled libraries are also removed from BlockedMediaFolders (Jellyfin) and ExcludedSubFolders. To give access to all libraries, set Policy.EnableAllFolders instead.
+func (mb *MediaBrowser) SetUserLibraries(userID string, names []string) error {
+	return mb.SetUserLibrariesContext(context.Background(), userID, names)
+}
+
+// SetUserLibrariesContext is SetUserLibraries with a context controlling the requests.
+func (mb *MediaBrowser) SetUserLibrariesContext(ctx context.Context, userID string, names []string) error {
+	libraries, _, err := mb.GetLibrariesContext(ctx)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(names))
+	// Every form of ID (and the name, which older servers used in BlockedMediaFolders) of the enabled libraries.
+	enabled := map[string]bool{}
+	for _, name := range names {
+		found := false
+		for _, library := range libraries {
+			if !strings.EqualFold(library.Name, name) {
+				continue
+			}
+			found = true
+			if id := mb.policyID(library); !enabled[normalizeID(id)] {
+				ids = append(ids, id)
+			}
+			for _, id := range libraryIDs(library) {
+				enabled[id] = true
+			}
+			enabled[normalizeID(library.Name)] = true
+			break
+		}
+		if !found {
+			return ErrLibraryNotFound{name: name}
+		}
+	}
+	return mb.PatchPolicyContext(ctx, userID, func(p *Policy) {
+		p.EnableAllFolders = false
+		p.EnabledFolders = ids
+		blocked := []interface{}{}
+		for _, folder := range p.BlockedMediaFolders {
+			if id, ok := folder.(string); ok && enabled[normalizeID(id)] {
+				continue
+			}
+			blocked = append(blocked, folder)
+		}
+		p.BlockedMediaFolders = blocked
+		// Entries are "<library ID>_<folder ID>".
+		excluded := []interface{}{}
+		for _, folder := range p.ExcludedSubFolders {
+			if s, ok := folder.(string); ok {
+				if sep := strings.Index(s, "_"); sep != -1 && enabled[normalizeID(s[:sep])] {
+					continue
+				}
+			}
+			excluded = append(excluded, folder)
+		}
+		p.ExcludedSubFolders = excluded
+	})
+}
@@ -0,0 +1,82 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUserLibraries(t *testing.T) {
+	var posted Policy
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Library/VirtualFolders":
+			w.Write([]byte(`[{"Name":"Movies","ItemId":"f137a2dd21bbc1b99aa5c0f6bf02a805"},{"Name":"Shows","ItemId":"a656b907eb3a73532e40e44b968d0225"}]`))
+		case "/users/aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa":
+			w.Write([]byte(`{"Id":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa","Policy":{"EnableAllFolders":true,"BlockedMediaFolders":["a656b907-eb3a-7353-2e40-e44b968d0225","other"],"ExcludedSubFolders":["a656b907-eb3a-7353-2e40-e44b968d0225_123","other_456"]}}`))
+		case "/Users/aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa/Policy":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+	mb.Hyphens = true
+
+	id, err := mb.LibraryIDByName("shows")
+	if err != nil || id != "a656b907-eb3a-7353-2e40-e44b968d0225" {
+		t.Errorf("LibraryIDByName = %q, %v", id, err)
+	}
+	if name, err := mb.LibraryNameByID("F137A2DD21BBC1B99AA5C0F6BF02A805"); err != nil || name != "Movies" {
+		t.Errorf("LibraryNameByID = %q, %v", name, err)
+	}
+	if _, err := mb.LibraryIDByName("Music"); err == nil {
+		t.Errorf("expected ErrLibraryNotFound")
+	}
+
+	if err := mb.SetUserLibraries("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", []string{"Shows", "movies"}); err != nil {
+		t.Fatalf("SetUserLibraries failed: %v", err)
+	}
+	if posted.EnableAllFolders || len(posted.EnabledFolders) != 2 || posted.EnabledFolders[0] != id {
+		t.Errorf("unexpected policy: %+v", posted)
+	}
+	if len(posted.BlockedMediaFolders) != 1 || posted.BlockedMediaFolders[0] != "other" {
+		t.Errorf("enabled library left blocked: %v", posted.BlockedMediaFolders)
+	}
+	if len(posted.ExcludedSubFolders) != 1 || posted.ExcludedSubFolders[0] != "other_456" {
+		t.Errorf("enabled library left with excluded folders: %v", posted.ExcludedSubFolders)
+	}
+}
+
+func TestEmbyUserLibraries(t *testing.T) {
+	var posted Policy
+	mb, _ := newTestServer(t, EmbyServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Library/VirtualFolders":
+			w.Write([]byte(`[{"Name":"Movies","ItemId":"3","Guid":"5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2"},{"Name":"Shows","ItemId":"7"}]`))
+		case "/users/12":
+			w.Write([]byte(`{"Id":"12","Policy":{"EnableAllFolders":true,"ExcludedSubFolders":["5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2_20","9_30"]}}`))
+		case "/Users/12/Policy":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+
+	// Emby policies refer to libraries by Guid, where the server gives one.
+	if id, err := mb.LibraryIDByName("movies"); err != nil || id != "5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2" {
+		t.Errorf("LibraryIDByName = %q, %v", id, err)
+	}
+	if name, err := mb.LibraryNameByID("3"); err != nil || name != "Movies" {
+		t.Errorf("LibraryNameByID = %q, %v", name, err)
+	}
+
+	if err := mb.SetUserLibraries("12", []string{"Movies", "Shows"}); err != nil {
+		t.Fatalf("SetUserLibraries failed: %v", err)
+	}
+	if len(posted.EnabledFolders) != 2 || posted.EnabledFolders[0] != "5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2" || posted.EnabledFolders[1] != "7" {
+		t.Errorf("unexpected EnabledFolders: %v", posted.EnabledFolders)
+	}
+	if len(posted.ExcludedSubFolders) != 1 || posted.ExcludedSubFolders[0] != "9_30" {
+		t.Errorf("enabled library left with excluded folders: %v", posted.ExcludedSubFolders)
+	}
+}
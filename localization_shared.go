@@ -0,0 +1,37 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetParentalRatings returns the parental ratings known to the server, for the server's configured country.
+func (mb *MediaBrowser) GetParentalRatings() ([]ParentalRating, error) {
+	return mb.GetParentalRatingsContext(context.Background())
+}
+
+// GetParentalRatingsContext is GetParentalRatings with a context controlling the request.
+func (mb *MediaBrowser) GetParentalRatingsContext(ctx context.Context) ([]ParentalRating, error) {
+	url := fmt.Sprintf("%s/Localization/ParentalRatings", mb.Server)
+	var result []ParentalRating
+	data, status, err := mb.getJSON(ctx, url, nil, &result, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParentalRatingValue returns the value for the rating with the given label (case-insensitive), for use in Policy.MaxParentalRating.
+// ratings should come from GetParentalRatings.
+func ParentalRatingValue(ratings []ParentalRating, label string) (int, bool) {
+	for _, rating := range ratings {
+		if strings.EqualFold(rating.Name, strings.TrimSpace(label)) {
+			return rating.Value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,31 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestParentalRatings(t *testing.T) {
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Localization/ParentalRatings" {
+			w.Write([]byte(`[{"Name":"G","Value":0},{"Name":"PG-13","Value":13},{"Name":"R","Value":17}]`))
+		}
+	}))
+	ratings, err := mb.GetParentalRatings()
+	if err != nil {
+		t.Fatalf("GetParentalRatings failed: %v", err)
+	}
+	if value, ok := ParentalRatingValue(ratings, "pg-13"); !ok || value != 13 {
+		t.Errorf("ParentalRatingValue(pg-13) = %d, %t", value, ok)
+	}
+	if _, ok := ParentalRatingValue(ratings, "NC-17"); ok {
+		t.Errorf("found a rating that doesn't exist")
+	}
+
+	var p Policy
+	json.Unmarshal([]byte(`{"BlockUnratedItems":["Movie","LiveTvProgram"]}`), &p)
+	if len(p.BlockUnratedItems) != 2 || p.BlockUnratedItems[1] != UnratedLiveTvProgram {
+		t.Errorf("unexpected BlockUnratedItems: %v", p.BlockUnratedItems)
+	}
+}
@@ -6,11 +6,13 @@ package mediabrowser
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -18,12 +20,16 @@ import (
 )
 
 // TimeoutHandler should recover from an http timeout or panic.
+//
+// Deprecated: Network failures are now returned as errors (ErrTimeout, ErrServerUnreachable),
+// so the handler is only a legacy hook for unexpected panics, and may be nil.
 type TimeoutHandler func()
 
 // NewNamedTimeoutHandler returns a new Timeout handler that logs the error.
 // name is the name of the server to use in the log (e.g Jellyfin/Emby)
 // addr is the address of the server being accessed
 // if noFail is false, the program will exit on a timeout.
+// Kept for compatibility, see TimeoutHandler.
 func NewNamedTimeoutHandler(name, addr string, noFail bool) TimeoutHandler {
 	return func() {
 		if r := recover(); r != nil {
@@ -54,6 +60,8 @@ type ServerInfo struct {
 }
 
 // MediaBrowser is an api instance of Jellyfin/Emby.
+// Its methods are safe for concurrent use by multiple goroutines. The exported fields reflect its state for reading,
+// and should only be changed (if at all) before the MediaBrowser is shared, as should the transport (see SetTransport).
 type MediaBrowser struct {
 	Server        string
 	client        string
@@ -66,21 +74,33 @@ type MediaBrowser struct {
 	ServerInfo    ServerInfo
 	Username      string
 	password      string
+	apiKey        string
 	Authenticated bool
 	AccessToken   string
 	userID        string
 	httpClient    *http.Client
 	loginParams   map[string]string
-	userCache     []User
-	syncLock      sync.Mutex
-	syncing       bool
+	authSync      *authSync // In-progress login, if any.
+	// Guards auth, header, Username, password, apiKey, Authenticated, AccessToken, userID, loginParams & authSync.
+	authLock  sync.RWMutex
+	userCache []User
+	// Guards userCache, usersByID, usersByName, userSync, liveUserCache, liveUserCacheLoaded, libraryCache, CacheExpiry, LibraryCacheExpiry, Hyphens,
+	// cache, userCacheEntry & libraryCacheEntry.
+	cacheLock sync.RWMutex
+	// In-progress refresh of the user cache, if any.
+	userSync *cacheSync
 	// Map of IDs to array indices
 	usersByID map[string]int
 	// Map of lowercase names to array indices
 	usersByName                     map[string]int
+	liveUserCache                   bool // Kept up to date by events, see EnableLiveUserCache.
+	liveUserCacheLoaded             bool // Loaded since the socket connected, without changes during the load, so events alone keep it up to date.
 	libraryCache                    []VirtualFolder
 	CacheExpiry, LibraryCacheExpiry time.Time // first is UserCacheExpiry, keeping name for compatability
 	cacheLength                     int
+	cache                           Cache     // Backing store for the user & library lists, see SetCache.
+	userCacheEntry                  time.Time // Expiry of the entry in cache that userCache was last loaded from or stored to.
+	libraryCacheEntry               time.Time // Likewise for libraryCache.
 	noFail                          bool
 	Hyphens                         bool
 	serverType                      serverType
@@ -98,6 +118,9 @@ func NewServer(st serverType, server, client, version, device, deviceID string,
 	mb.device = device
 	mb.deviceID = deviceID
 	mb.useragent = fmt.Sprintf("%s/%s", client, version)
+	if timeoutHandler == nil {
+		timeoutHandler = func() {}
+	}
 	mb.timeoutHandler = timeoutHandler
 	mb.Authenticated = false
 	mb.auth = fmt.Sprintf("MediaBrowser Client=\"%s\", Device=\"%s\", DeviceId=\"%s\", Version=\"%s\"", client, device, deviceID, version)
@@ -119,7 +142,7 @@ func NewServer(st serverType, server, client, version, device, deviceID string,
 	if err != nil {
 		return nil, err
 	}
-	resp, err := mb.httpClient.Do(req)
+	resp, err := mb.do(req)
 	defer mb.timeoutHandler()
 	if err == nil {
 		defer resp.Body.Close()
@@ -131,87 +154,223 @@ func NewServer(st serverType, server, client, version, device, deviceID string,
 	return mb, nil
 }
 
+// NewServerWithAPIKey returns a new Mediabrowser object authenticated with the given server API key. See SetAPIKey.
+func NewServerWithAPIKey(st serverType, server, client, version, device, deviceID, apiKey string, timeoutHandler TimeoutHandler, cacheTimeout int) (*MediaBrowser, error) {
+	mb, err := NewServer(st, server, client, version, device, deviceID, timeoutHandler, cacheTimeout)
+	if err != nil {
+		return nil, err
+	}
+	mb.SetAPIKey(apiKey)
+	return mb, nil
+}
+
 // SetTransport sets the HTTP transport to be used for all requests. Can be used to set a proxy.
 func (mb *MediaBrowser) SetTransport(t *http.Transport) {
 	mb.httpClient.Transport = t
 }
 
 func bodyToString(resp *http.Response) string {
-	var data io.Reader
-	encoding := resp.Header.Get("Content-Encoding")
-	switch encoding {
-	case "gzip":
-		data, _ = gzip.NewReader(resp.Body)
-	default:
-		data = resp.Body
+	data, err := decodedBody(resp)
+	if err != nil {
+		return ""
 	}
 	buf := new(strings.Builder)
 	io.Copy(buf, data)
 	return buf.String()
 }
 
-func (mb *MediaBrowser) get(url string, params map[string]string) (string, int, error) {
-	var req *http.Request
-	if params != nil {
-		jsonParams, _ := json.Marshal(params)
-		req, _ = http.NewRequest("GET", url, bytes.NewBuffer(jsonParams))
-	} else {
-		req, _ = http.NewRequest("GET", url, nil)
+// decodedBody returns the response body, decompressed if necessary.
+func decodedBody(resp *http.Response) (io.Reader, error) {
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		return gzip.NewReader(resp.Body)
+	default:
+		return resp.Body, nil
+	}
+}
+
+// stream is a response body being read by the caller. Close must be called once done.
+type stream struct {
+	io.Reader
+	resp *http.Response
+	mb   *MediaBrowser
+}
+
+func (s *stream) Close() error {
+	defer s.mb.timeoutHandler()
+	return s.resp.Body.Close()
+}
+
+// newRequest creates a request with the standard headers (including authorization) attached.
+func (mb *MediaBrowser) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
 	}
+	mb.authLock.RLock()
+	defer mb.authLock.RUnlock()
 	for name, value := range mb.header {
 		req.Header.Add(name, value)
 	}
+	return req, nil
+}
+
+// do performs the request, converting any transport-level failure into an ErrTimeout or ErrServerUnreachable.
+// A cancelled context is returned as-is (context.Canceled).
+func (mb *MediaBrowser) do(req *http.Request) (*http.Response, error) {
 	resp, err := mb.httpClient.Do(req)
+	if err == nil {
+		return resp, nil
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil, context.Canceled
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return nil, ErrTimeout{Err: err}
+	}
+	return nil, ErrServerUnreachable{Err: err}
+}
+
+func (mb *MediaBrowser) get(ctx context.Context, url string, params map[string]string) (string, int, error) {
+	return mb.getWithRetry(ctx, url, params, true)
+}
+
+// getWithRetry is get, re-authenticating and retrying once after a 401 if retry is true.
+func (mb *MediaBrowser) getWithRetry(ctx context.Context, url string, params map[string]string, retry bool) (string, int, error) {
+	var body io.Reader
+	if params != nil {
+		jsonParams, _ := json.Marshal(params)
+		body = bytes.NewBuffer(jsonParams)
+	}
+	req, err := mb.newRequest(ctx, "GET", url, body)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := mb.do(req)
 	defer mb.timeoutHandler()
+	if err != nil {
+		return "", 0, err
+	}
 	defer resp.Body.Close()
-	if err != nil || resp.StatusCode != 200 {
-		if resp.StatusCode == 401 && mb.Authenticated {
-			mb.Authenticated = false
-			_, authErr := mb.Authenticate(mb.Username, mb.password)
-			if authErr == nil {
-				v1, v2, v3 := mb.get(url, params)
-				return v1, v2, v3
-			}
+	if resp.StatusCode == 401 && retry && mb.reauthenticate(ctx, req.Header.Get("Authorization")) {
+		return mb.getWithRetry(ctx, url, params, false)
+	}
+	return bodyToString(resp), resp.StatusCode, nil
+}
+
+// getStream is get, but returns the body to be read as the caller goes, rather than all at once.
+// If the status isn't 200, the body is instead returned as data for error handling, and the stream is nil.
+func (mb *MediaBrowser) getStream(ctx context.Context, url string, params map[string]string) (body io.ReadCloser, data string, status int, err error) {
+	return mb.getStreamWithRetry(ctx, url, params, true)
+}
+
+func (mb *MediaBrowser) getStreamWithRetry(ctx context.Context, url string, params map[string]string, retry bool) (io.ReadCloser, string, int, error) {
+	var reqBody io.Reader
+	if params != nil {
+		jsonParams, _ := json.Marshal(params)
+		reqBody = bytes.NewBuffer(jsonParams)
+	}
+	req, err := mb.newRequest(ctx, "GET", url, reqBody)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	resp, err := mb.do(req)
+	if err != nil {
+		defer mb.timeoutHandler()
+		return nil, "", 0, err
+	}
+	if resp.StatusCode != 200 {
+		defer mb.timeoutHandler()
+		defer resp.Body.Close()
+		if resp.StatusCode == 401 && retry && mb.reauthenticate(ctx, req.Header.Get("Authorization")) {
+			return mb.getStreamWithRetry(ctx, url, params, false)
 		}
+		return nil, bodyToString(resp), resp.StatusCode, nil
 	}
-	//var respData map[string]interface{}
-	//json.NewDecoder(data).Decode(&respData)
-	if resp != nil {
-		return bodyToString(resp), resp.StatusCode, err
+	data, err := decodedBody(resp)
+	if err != nil {
+		defer mb.timeoutHandler()
+		resp.Body.Close()
+		return nil, "", resp.StatusCode, err
 	}
-	return "", 0, err
+	return &stream{Reader: data, resp: resp, mb: mb}, "", resp.StatusCode, nil
+}
+
+// getJSON is get, but decodes a 200 response straight from the body into v, rather than going through a string.
+// Any other response is returned as data for error handling, as with get. If raw isn't nil, the body is also copied into it.
+func (mb *MediaBrowser) getJSON(ctx context.Context, url string, params map[string]string, v interface{}, raw *bytes.Buffer) (string, int, error) {
+	body, data, status, err := mb.getStream(ctx, url, params)
+	if err != nil || body == nil {
+		return data, status, err
+	}
+	defer body.Close()
+	var r io.Reader = body
+	if raw != nil {
+		r = io.TeeReader(body, raw)
+	}
+	return "", status, json.NewDecoder(r).Decode(v)
 }
 
-func (mb *MediaBrowser) post(url string, data interface{}, response bool) (string, int, error) {
+func (mb *MediaBrowser) post(ctx context.Context, url string, data interface{}, response bool) (string, int, error) {
+	return mb.postWithRetry(ctx, url, data, response, true)
+}
+
+// postWithRetry is post, re-authenticating and retrying once after a 401 if retry is true.
+func (mb *MediaBrowser) postWithRetry(ctx context.Context, url string, data interface{}, response, retry bool) (string, int, error) {
 	params, _ := json.Marshal(data)
 	// fmt.Printf("Data: %s\n", string(params))
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(params))
-	for name, value := range mb.header {
-		req.Header.Add(name, value)
+	req, err := mb.newRequest(ctx, "POST", url, bytes.NewBuffer(params))
+	if err != nil {
+		return "", 0, err
 	}
-	resp, err := mb.httpClient.Do(req)
+	resp, err := mb.do(req)
 	defer mb.timeoutHandler()
+	if err != nil {
+		return "", 0, err
+	}
 	defer resp.Body.Close()
-	if err != nil || resp.StatusCode != 200 {
-		if resp.StatusCode == 401 && mb.Authenticated {
-			mb.Authenticated = false
-			_, authErr := mb.Authenticate(mb.Username, mb.password)
-			if authErr == nil {
-				v1, v2, v3 := mb.post(url, data, response)
-				return v1, v2, v3
-			}
+	if resp.StatusCode != 200 {
+		if resp.StatusCode == 401 && retry && mb.reauthenticate(ctx, req.Header.Get("Authorization")) {
+			return mb.postWithRetry(ctx, url, data, response, false)
 		}
-		return "", resp.StatusCode, err
+		return "", resp.StatusCode, nil
 	}
 	if response {
-		defer resp.Body.Close()
 		return bodyToString(resp), resp.StatusCode, nil
 	}
 	return "", resp.StatusCode, nil
 }
 
+func (mb *MediaBrowser) delete(ctx context.Context, url string) (string, int, error) {
+	return mb.deleteWithRetry(ctx, url, true)
+}
+
+// deleteWithRetry is delete, re-authenticating and retrying once after a 401 if retry is true.
+func (mb *MediaBrowser) deleteWithRetry(ctx context.Context, url string, retry bool) (string, int, error) {
+	req, err := mb.newRequest(ctx, "DELETE", url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := mb.do(req)
+	defer mb.timeoutHandler()
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 401 && retry && mb.reauthenticate(ctx, req.Header.Get("Authorization")) {
+		return mb.deleteWithRetry(ctx, url, false)
+	}
+	return bodyToString(resp), resp.StatusCode, nil
+}
+
 // Authenticate attempts to authenticate using a username & password
 func (mb *MediaBrowser) Authenticate(username, password string) (User, error) {
+	return mb.AuthenticateContext(context.Background(), username, password)
+}
+
+// AuthenticateContext is Authenticate with a context controlling the request.
+func (mb *MediaBrowser) AuthenticateContext(ctx context.Context, username, password string) (User, error) {
 	// Yes, i know these are useless
 	if username == "" {
 		return User{}, errors.New("blank username not allowed")
@@ -219,45 +378,56 @@ func (mb *MediaBrowser) Authenticate(username, password string) (User, error) {
 	if password == "" {
 		return User{}, errors.New("blank password not allowed")
 	}
-	mb.Authenticated = false
-	mb.Username = username
-	mb.password = password
-	mb.loginParams = map[string]string{
+	loginParams := map[string]string{
 		"Username": username,
 		"Pw":       password,
 		"Password": password,
 	}
+	mb.authLock.Lock()
+	mb.Username = username
+	mb.password = password
+	mb.loginParams = loginParams
+	mb.authLock.Unlock()
+	user, err := mb.authenticate(ctx, loginParams)
+	if err != nil {
+		// Only cleared now, so requests made in the meantime carry on with the old token rather than each logging in again.
+		mb.authLock.Lock()
+		mb.Authenticated = false
+		mb.authLock.Unlock()
+	}
+	return user, err
+}
+
+// authenticate logs in with the given parameters, storing the new token on success.
+func (mb *MediaBrowser) authenticate(ctx context.Context, loginParams map[string]string) (User, error) {
 	buffer := &bytes.Buffer{}
 	encoder := json.NewEncoder(buffer)
 	encoder.SetEscapeHTML(false)
-	err := encoder.Encode(mb.loginParams)
+	err := encoder.Encode(loginParams)
 	if err != nil {
 		return User{}, err
 	}
 	// loginParams, _ := json.Marshal(jf.loginParams)
 	url := fmt.Sprintf("%s/Users/authenticatebyname", mb.Server)
-	req, err := http.NewRequest("POST", url, buffer)
+	req, err := mb.newRequest(ctx, "POST", url, buffer)
 	defer mb.timeoutHandler()
 	if err != nil {
 		return User{}, err
 	}
-	for name, value := range mb.header {
-		req.Header.Add(name, value)
-	}
-	resp, err := mb.httpClient.Do(req)
+	resp, err := mb.do(req)
 	if err != nil {
 		return User{}, err
 	}
+	defer resp.Body.Close()
 	// Jellyfin likes to return 400 for a lot of things, even if the api docs don't say so.
 	if resp.StatusCode == 400 {
 		err = ErrUnauthorized{}
-	} else if customErr := mb.genericErr(resp.StatusCode, ""); customErr != nil {
+	} else if customErr := mb.genericErr(resp.StatusCode, ""); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
 		return User{}, err
 	}
-	defer resp.Body.Close()
 	var d io.Reader
 	switch resp.Header.Get("Content-Encoding") {
 	case "gzip":
@@ -282,13 +452,126 @@ func (mb *MediaBrowser) Authenticate(username, password string) (User, error) {
 		return User{}, err
 	}
 	json.Unmarshal(ju, &user)
-	mb.AccessToken = respData["AccessToken"].(string)
+	token, ok := respData["AccessToken"].(string)
+	if !ok {
+		return User{}, ErrUnknown{DetailedError: DetailedError{Code: resp.StatusCode, Verbose: mb.Verbose, Data: string(data)}}
+	}
+	mb.authLock.Lock()
+	defer mb.authLock.Unlock()
+	mb.AccessToken = token
 	mb.userID = user.ID
-	mb.auth = fmt.Sprintf("MediaBrowser Client=\"%s\", Device=\"%s\", DeviceId=\"%s\", Version=\"%s\", Token=\"%s\"", mb.client, mb.device, mb.deviceID, mb.version, mb.AccessToken)
+	mb.apiKey = ""
+	delete(mb.header, "X-Emby-Token")
+	mb.setToken(mb.AccessToken)
+	mb.Authenticated = true
+	return user, nil
+}
+
+// setToken includes the given access token in the authorization headers. authLock must be held.
+func (mb *MediaBrowser) setToken(token string) {
+	mb.auth = fmt.Sprintf("MediaBrowser Client=\"%s\", Device=\"%s\", DeviceId=\"%s\", Version=\"%s\", Token=\"%s\"", mb.client, mb.device, mb.deviceID, mb.version, token)
 	mb.header["Authorization"] = mb.auth
 	mb.header["X-Emby-Authorization"] = mb.auth
+}
+
+// SetAPIKey switches to authenticating with the given server API key (created in the dashboard or with CreateAPIKey), rather than a username & password.
+// No request is made, so an invalid key will only be noticed on the next call, which will return ErrAPIKeyRevoked.
+// Calling Authenticate afterwards switches back to username & password authentication.
+func (mb *MediaBrowser) SetAPIKey(key string) {
+	mb.authLock.Lock()
+	defer mb.authLock.Unlock()
+	mb.apiKey = key
+	mb.AccessToken = key
+	mb.Username, mb.password, mb.loginParams = "", "", nil
+	mb.userID = ""
+	mb.setToken(key)
+	mb.header["X-Emby-Token"] = key
 	mb.Authenticated = true
-	return user, nil
+}
+
+// ensureAuthenticated authenticates with the stored credentials if not already authenticated.
+// If an API key is being used, ErrAPIKeyRevoked is returned instead as there's nothing to retry with.
+func (mb *MediaBrowser) ensureAuthenticated(ctx context.Context) error {
+	mb.authLock.RLock()
+	authenticated, apiKey, username, password := mb.Authenticated, mb.apiKey, mb.Username, mb.password
+	mb.authLock.RUnlock()
+	if authenticated {
+		return nil
+	}
+	if apiKey != "" {
+		return ErrAPIKeyRevoked{}
+	}
+	return mb.login(ctx, username, password)
+}
+
+// authSync represents an in-progress login, shared by everyone who needs it.
+type authSync struct {
+	done chan struct{} // Closed once the login is finished, after which err is set.
+	err  error
+}
+
+// login authenticates with the given credentials. Concurrent callers share a single login.
+func (mb *MediaBrowser) login(ctx context.Context, username, password string) error {
+	for {
+		mb.authLock.Lock()
+		call := mb.authSync
+		if call == nil {
+			call = &authSync{done: make(chan struct{})}
+			mb.authSync = call
+			mb.authLock.Unlock()
+			_, call.err = mb.AuthenticateContext(ctx, username, password)
+			mb.authLock.Lock()
+			mb.authSync = nil
+			mb.authLock.Unlock()
+			close(call.done)
+			return call.err
+		}
+		mb.authLock.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// If the login was only cancelled by whoever started it, try again with our context.
+		if errors.Is(call.err, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+		return call.err
+	}
+}
+
+// reauthenticate is called after a request made with the given Authorization header got a 401, returning true if the request should be retried.
+func (mb *MediaBrowser) reauthenticate(ctx context.Context, auth string) bool {
+	mb.authLock.RLock()
+	apiKey, current, username, password := mb.apiKey, mb.auth, mb.Username, mb.password
+	mb.authLock.RUnlock()
+	if apiKey != "" {
+		// An API key can't be renewed, so leave the 401 to be returned as ErrAPIKeyRevoked.
+		return false
+	}
+	if username == "" {
+		// Never logged in, so there's nothing to log in again with.
+		return false
+	}
+	if auth != current {
+		// Someone else has already logged in again since the request was made.
+		return true
+	}
+	return mb.login(ctx, username, password) == nil
+}
+
+// usingAPIKey returns whether we're authenticated with an API key rather than a username & password.
+func (mb *MediaBrowser) usingAPIKey() bool {
+	mb.authLock.RLock()
+	defer mb.authLock.RUnlock()
+	return mb.apiKey != ""
+}
+
+// credentials returns the access token and login parameters currently in use.
+func (mb *MediaBrowser) credentials() (token string, loginParams map[string]string) {
+	mb.authLock.RLock()
+	defer mb.authLock.RUnlock()
+	return mb.AccessToken, mb.loginParams
 }
 
 // MustAuthenticateOptions is used to control the behaviour of the MustAuthenticate method.
@@ -300,55 +583,114 @@ type MustAuthenticateOptions struct {
 
 // MustAuthenticate attempts to authenticate using a username & password, with configurable retries in the event of failure.
 func (mb *MediaBrowser) MustAuthenticate(username, password string, opts MustAuthenticateOptions) (user User, err error) {
+	return mb.MustAuthenticateContext(context.Background(), username, password, opts)
+}
+
+// MustAuthenticateContext is MustAuthenticate with a context, which also cuts short the wait between retries.
+func (mb *MediaBrowser) MustAuthenticateContext(ctx context.Context, username, password string, opts MustAuthenticateOptions) (user User, err error) {
 	for i := 0; i < opts.RetryCount; i++ {
-		user, err = mb.Authenticate(username, password)
+		user, err = mb.AuthenticateContext(ctx, username, password)
 		if err == nil {
 			return
 		}
 		if opts.LogFailures {
 			log.Printf("Failed to authenticate on attempt %d, retrying in %s...\n", i+1, opts.RetryGap)
 		}
-		time.Sleep(opts.RetryGap)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(opts.RetryGap):
+		}
 	}
 	return
 }
 
 // DeleteUser deletes the user corresponding to the provided ID.
 func (mb *MediaBrowser) DeleteUser(userID string) error {
-	if !mb.Authenticated {
-		_, err := mb.Authenticate(mb.Username, mb.password)
-		if err != nil {
-			return err
-		}
+	return mb.DeleteUserContext(context.Background(), userID)
+}
+
+// DeleteUserContext is DeleteUser with a context controlling the request.
+func (mb *MediaBrowser) DeleteUserContext(ctx context.Context, userID string) error {
+	if err := mb.ensureAuthenticated(ctx); err != nil {
+		return err
 	}
 
+	var err error
 	if mb.serverType == JellyfinServer {
-		return jfDeleteUser(mb, userID)
+		err = jfDeleteUser(ctx, mb, userID)
+	} else {
+		err = embyDeleteUser(ctx, mb, userID)
 	}
-	return embyDeleteUser(mb, userID)
+	if err == nil {
+		mb.uncacheUser(userID)
+	}
+	return err
 }
 
 // NewUser creates a new user with the provided username and password.
 func (mb *MediaBrowser) NewUser(username, password string) (User, error) {
-	if !mb.Authenticated {
-		_, err := mb.Authenticate(mb.Username, mb.password)
-		if err != nil {
-			return User{}, err
-		}
+	return mb.NewUserContext(context.Background(), username, password)
+}
+
+// NewUserContext is NewUser with a context controlling the request(s).
+func (mb *MediaBrowser) NewUserContext(ctx context.Context, username, password string) (User, error) {
+	if err := mb.ensureAuthenticated(ctx); err != nil {
+		return User{}, err
 	}
 
+	var user User
+	var err error
 	if mb.serverType == JellyfinServer {
-		return jfNewUser(mb, username, password)
+		user, err = jfNewUser(ctx, mb, username, password)
+	} else {
+		user, err = embyNewUser(ctx, mb, username, password)
+	}
+	if err == nil {
+		mb.cacheUser(user)
 	}
-	return embyNewUser(mb, username, password)
+	return user, err
 }
 
 // ResetPassword resets a user's password by setting it to the given PIN,
 // which is generated when a user attempts to reset on the login page.
 // Only supported on Jellyfin, will return (PasswordResetResponse, -1, nil) on Emby.
 func (mb *MediaBrowser) ResetPassword(pin string) (PasswordResetResponse, error) {
+	return mb.ResetPasswordContext(context.Background(), pin)
+}
+
+// ResetPasswordContext is ResetPassword with a context controlling the request.
+func (mb *MediaBrowser) ResetPasswordContext(ctx context.Context, pin string) (PasswordResetResponse, error) {
 	if mb.serverType == EmbyServer {
 		return PasswordResetResponse{}, nil
 	}
-	return jfResetPassword(mb, pin)
+	return jfResetPassword(ctx, mb, pin)
+}
+
+// EndSession logs out the session with the given ID, by removing the device it belongs to.
+// Other sessions on the same device will also be logged out.
+func (mb *MediaBrowser) EndSession(sessionID string) error {
+	return mb.EndSessionContext(context.Background(), sessionID)
+}
+
+// EndSessionContext is EndSession with a context controlling the requests.
+func (mb *MediaBrowser) EndSessionContext(ctx context.Context, sessionID string) error {
+	sessions, err := mb.GetSessionsContext(ctx, SessionFilter{})
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return mb.endSession(ctx, session)
+		}
+	}
+	return NotFound
+}
+
+func (mb *MediaBrowser) endSession(ctx context.Context, session SessionInfo) error {
+	if mb.serverType == JellyfinServer {
+		return jfEndSession(ctx, mb, session)
+	}
+	return embyEndSession(ctx, mb, session)
 }
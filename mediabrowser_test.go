@@ -0,0 +1,320 @@
+package mediabrowser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a MediaBrowser pointed at a local stand-in for Jellyfin, served by handler.
+func newTestServer(t *testing.T, st serverType, handler http.Handler) (*MediaBrowser, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	mb, err := NewServer(st, server.URL, "mediabrowser-test", "v0.0.0", "test", "test-id", nil, 30)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	return mb, server
+}
+
+func TestUnreachableServer(t *testing.T) {
+	// Grab a free port and close it again, so nothing is listening there.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "http://" + l.Addr().String()
+	l.Close()
+
+	mb, err := NewServer(JellyfinServer, addr, "mediabrowser-test", "v0.0.0", "test", "test-id", nil, 30)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	_, err = mb.GetUsers(true)
+	var unreachable ErrServerUnreachable
+	if !errors.As(err, &unreachable) {
+		t.Fatalf("expected ErrServerUnreachable, got %T: %v", err, err)
+	}
+	if _, err := mb.DeleteLibrary("Movies"); !errors.As(err, &unreachable) {
+		t.Errorf("DeleteLibrary: expected ErrServerUnreachable, got %T: %v", err, err)
+	}
+	if err := mb.DeleteUser("abc"); err == nil {
+		t.Errorf("DeleteUser: expected an error")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/System/Info/Public" {
+			return
+		}
+		<-release
+	}))
+	defer close(release)
+	mb.httpClient.Timeout = 50 * time.Millisecond
+
+	_, err := mb.GetUsers(true)
+	var timeout ErrTimeout
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected ErrTimeout, got %T: %v", err, err)
+	}
+}
+
+// roundTripFunc lets a function stand in for an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// panicReader panics when read.
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) { panic("read failed") }
+func (panicReader) Close() error             { return nil }
+
+func TestRecoveredPanic(t *testing.T) {
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mb.timeoutHandler = func() { recover() }
+	mb.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: panicReader{}, Request: r}, nil
+	})
+	// The recovered request returns no status, which mustn't be mistaken for success.
+	var unknown ErrUnknown
+	if _, err := mb.DeleteLibrary("Movies"); !errors.As(err, &unknown) {
+		t.Errorf("DeleteLibrary: expected ErrUnknown, got %T: %v", err, err)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/System/Info/Public" {
+			return
+		}
+		<-release
+	}))
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := mb.SetPolicyContext(ctx, "abc", Policy{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SetPolicy: expected context.Canceled, got %T: %v", err, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := mb.GetUsersContext(ctx, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetUsers: expected context.DeadlineExceeded, got %T: %v", err, err)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	const key = "0123456789abcdef"
+	revoked := false
+	authCalls := 0
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/System/Info/Public":
+			return
+		case "/Users/authenticatebyname":
+			authCalls++
+			w.WriteHeader(401)
+			return
+		}
+		if revoked || r.Header.Get("X-Emby-Token") != key {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`[{"Name":"john","Id":"a1b2c3d4e5f6"}]`))
+	}))
+	mb.SetAPIKey(key)
+
+	users, err := mb.GetUsers(false)
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "john" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	revoked = true
+	err = mb.SetPolicy("a1b2c3d4e5f6", Policy{})
+	if _, ok := err.(ErrAPIKeyRevoked); !ok {
+		t.Fatalf("expected ErrAPIKeyRevoked, got %T: %v", err, err)
+	}
+	if err := mb.DeleteUser("a1b2c3d4e5f6"); err == nil {
+		t.Fatalf("expected DeleteUser to fail after revocation")
+	}
+	if authCalls != 0 {
+		t.Errorf("password authentication was attempted %d time(s) in API key mode", authCalls)
+	}
+}
+
+func TestConcurrentReauthentication(t *testing.T) {
+	var lock sync.Mutex
+	logins := 0
+	valid := ""
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/System/Info/Public":
+			return
+		case "/Users/authenticatebyname":
+			// Slow enough for the other requests to pile up behind the login.
+			time.Sleep(20 * time.Millisecond)
+			lock.Lock()
+			logins++
+			valid = fmt.Sprintf("token%d", logins)
+			token := valid
+			lock.Unlock()
+			w.Write([]byte(`{"User":{"Id":"a1b2c3d4e5f6","Name":"admin"},"AccessToken":"` + token + `"}`))
+			return
+		}
+		lock.Lock()
+		ok := strings.Contains(r.Header.Get("Authorization"), `Token="`+valid+`"`)
+		lock.Unlock()
+		if !ok {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"Items":[],"TotalRecordCount":0}`))
+	}))
+	if _, err := mb.Authenticate("admin", "password"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	// Expire the token, so every request below gets a 401.
+	lock.Lock()
+	valid = "expired"
+	lock.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := mb.GetItems(ItemsQuery{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetItems failed: %v", err)
+		}
+	}
+	lock.Lock()
+	if logins != 2 {
+		t.Errorf("logged in %d times, expected 2", logins)
+	}
+	valid = "expired"
+	lock.Unlock()
+	// DELETEs should log in again too.
+	if _, err := mb.DeleteLibrary("Movies"); err != nil {
+		t.Errorf("DeleteLibrary failed: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if logins != 3 {
+		t.Errorf("logged in %d times, expected 3", logins)
+	}
+}
+
+// largeUserList returns a gzipped JSON array of n users, like a big server's /Users.
+func largeUserList(b *testing.B, n int) []byte {
+	b.Helper()
+	users := make([]User, n)
+	for i := range users {
+		users[i] = User{ID: fmt.Sprintf("%032x", i), Name: fmt.Sprintf("user%d", i), Policy: Policy{EnabledFolders: []string{"a", "b"}}}
+	}
+	data, err := json.Marshal(users)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+func gzipResponse(body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Encoding": {"gzip"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+// benchmarkServer returns a MediaBrowser whose requests are all answered with the given gzipped body, without going over the network.
+func benchmarkServer(b *testing.B, body []byte) *MediaBrowser {
+	b.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	mb, err := NewServer(JellyfinServer, server.URL, "mediabrowser-test", "v0.0.0", "test", "test-id", nil, 30)
+	if err != nil {
+		b.Fatal(err)
+	}
+	mb.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := gzipResponse(body)
+		resp.Request = r
+		return resp, nil
+	})
+	return mb
+}
+
+// BenchmarkDecodeViaString is how responses were decoded before getJSON: read into a string by get, then unmarshalled.
+func BenchmarkDecodeViaString(b *testing.B) {
+	mb := benchmarkServer(b, largeUserList(b, 2000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _, err := mb.get(context.Background(), mb.Server+"/users", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var users []User
+		if err := json.Unmarshal([]byte(data), &users); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetJSON decodes responses with getJSON, straight from the body, both alone and copying the body for a shared cache.
+func BenchmarkGetJSON(b *testing.B) {
+	mb := benchmarkServer(b, largeUserList(b, 2000))
+	for _, shared := range []bool{false, true} {
+		name := "NoCache"
+		if shared {
+			name = "SharedCache"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var raw *bytes.Buffer
+				if shared {
+					raw = &bytes.Buffer{}
+				}
+				var users []User
+				if _, _, err := mb.getJSON(context.Background(), mb.Server+"/users", nil, &users, raw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package mediabrowser
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type User struct {
 	Name                      string        `json:"Name"`
 	ServerID                  string        `json:"ServerId"`
@@ -15,9 +20,237 @@ type User struct {
 	Policy Policy `json:"Policy"`
 }
 
+// SessionInfo describes a client session, as returned by GetSessions.
 type SessionInfo struct {
-	RemoteEndpoint string `json:"RemoteEndPoint"`
-	UserID         string `json:"UserId"`
+	ID                    string           `json:"Id"`
+	RemoteEndpoint        string           `json:"RemoteEndPoint"`
+	UserID                string           `json:"UserId"`
+	UserName              string           `json:"UserName"`
+	Client                string           `json:"Client"`
+	ApplicationVersion    string           `json:"ApplicationVersion"`
+	DeviceID              string           `json:"DeviceId"`
+	DeviceName            string           `json:"DeviceName"`
+	DeviceType            string           `json:"DeviceType"`
+	LastActivityDate      Time             `json:"LastActivityDate"`
+	LastPlaybackCheckIn   Time             `json:"LastPlaybackCheckIn"`
+	PlayableMediaTypes    []string         `json:"PlayableMediaTypes"`
+	SupportedCommands     []string         `json:"SupportedCommands"`
+	SupportsMediaControl  bool             `json:"SupportsMediaControl"`
+	SupportsRemoteControl bool             `json:"SupportsRemoteControl"`
+	IsActive              bool             `json:"IsActive"`
+	NowPlayingItem        *BaseItem        `json:"NowPlayingItem,omitempty"`
+	PlayState             PlayState        `json:"PlayState"`
+	TranscodingInfo       *TranscodingInfo `json:"TranscodingInfo,omitempty"`
+	ServerID              string           `json:"ServerId"`
+}
+
+// SessionFilter narrows down the sessions returned by GetSessions. Zero values are ignored.
+type SessionFilter struct {
+	ControllableByUserID string // Only sessions the given user can remote control.
+	DeviceID             string
+	ActiveWithinSeconds  int
+}
+
+// PlaystateCommand controls playback on a session, see SendPlaystateCommand.
+type PlaystateCommand string
+
+const (
+	PlaystateStop          PlaystateCommand = "Stop"
+	PlaystatePause         PlaystateCommand = "Pause"
+	PlaystateUnpause       PlaystateCommand = "Unpause"
+	PlaystatePlayPause     PlaystateCommand = "PlayPause"
+	PlaystateNextTrack     PlaystateCommand = "NextTrack"
+	PlaystatePreviousTrack PlaystateCommand = "PreviousTrack"
+	PlaystateSeek          PlaystateCommand = "Seek"
+	PlaystateRewind        PlaystateCommand = "Rewind"
+	PlaystateFastForward   PlaystateCommand = "FastForward"
+)
+
+// GeneralCommand is a named command sent to a session's client, see SendGeneralCommand.
+// Not all clients support all commands, see SessionInfo.SupportedCommands.
+type GeneralCommand struct {
+	Name      string            `json:"Name"`
+	Arguments map[string]string `json:"Arguments,omitempty"`
+}
+
+// Some common GeneralCommand names.
+const (
+	CommandGoHome           = "GoHome"
+	CommandGoToSettings     = "GoToSettings"
+	CommandGoToSearch       = "GoToSearch"
+	CommandDisplayContent   = "DisplayContent"
+	CommandDisplayMessage   = "DisplayMessage"
+	CommandToggleFullscreen = "ToggleFullscreen"
+	CommandMute             = "Mute"
+	CommandUnmute           = "Unmute"
+	CommandSetVolume        = "SetVolume"
+)
+
+type messageCommand struct {
+	Header    string `json:"Header"`
+	Text      string `json:"Text"`
+	TimeoutMs int64  `json:"TimeoutMs,omitempty"`
+}
+
+// PlayState describes the playback state of a session.
+type PlayState struct {
+	PositionTicks       int64  `json:"PositionTicks"`
+	CanSeek             bool   `json:"CanSeek"`
+	IsPaused            bool   `json:"IsPaused"`
+	IsMuted             bool   `json:"IsMuted"`
+	VolumeLevel         int    `json:"VolumeLevel"`
+	AudioStreamIndex    *int   `json:"AudioStreamIndex,omitempty"`
+	SubtitleStreamIndex *int   `json:"SubtitleStreamIndex,omitempty"`
+	MediaSourceID       string `json:"MediaSourceId"`
+	PlayMethod          string `json:"PlayMethod"`
+	RepeatMode          string `json:"RepeatMode"`
+}
+
+// TranscodingInfo describes an ongoing transcode for a session.
+type TranscodingInfo struct {
+	AudioCodec               string   `json:"AudioCodec"`
+	VideoCodec               string   `json:"VideoCodec"`
+	Container                string   `json:"Container"`
+	IsVideoDirect            bool     `json:"IsVideoDirect"`
+	IsAudioDirect            bool     `json:"IsAudioDirect"`
+	Bitrate                  int      `json:"Bitrate"`
+	Framerate                float64  `json:"Framerate"`
+	CompletionPercentage     float64  `json:"CompletionPercentage"`
+	Width                    int      `json:"Width"`
+	Height                   int      `json:"Height"`
+	AudioChannels            int      `json:"AudioChannels"`
+	HardwareAccelerationType string   `json:"HardwareAccelerationType"`
+	TranscodeReasons         []string `json:"TranscodeReasons"`
+}
+
+// BaseItem is a (partial) representation of a media item.
+type BaseItem struct {
+	ID                string `json:"Id"`
+	Name              string `json:"Name"`
+	Type              string `json:"Type"`
+	MediaType         string `json:"MediaType"`
+	RunTimeTicks      int64  `json:"RunTimeTicks"`
+	ProductionYear    int    `json:"ProductionYear"`
+	SeriesName        string `json:"SeriesName"`
+	SeriesID          string `json:"SeriesId"`
+	ParentIndexNumber int    `json:"ParentIndexNumber"`
+	IndexNumber       int    `json:"IndexNumber"`
+	ParentID          string `json:"ParentId"`
+	OfficialRating    string `json:"OfficialRating"`
+	IsFolder          bool   `json:"IsFolder"`
+	// Only included if requested with ItemsQuery.Fields.
+	Tags        []string          `json:"Tags"`
+	Genres      []string          `json:"Genres"`
+	ProviderIDs map[string]string `json:"ProviderIds"`
+	// Map of image type (e.g. "Primary") to tag, used to build image URLs.
+	ImageTags map[string]string `json:"ImageTags"`
+	// Only included when queried for a user.
+	UserData *UserItemData `json:"UserData,omitempty"`
+}
+
+// UserItemData is a user's state for an item, like whether they've watched it.
+type UserItemData struct {
+	PlaybackPositionTicks int64    `json:"PlaybackPositionTicks"`
+	PlayCount             int      `json:"PlayCount"`
+	IsFavorite            bool     `json:"IsFavorite"`
+	Played                bool     `json:"Played"`
+	PlayedPercentage      float64  `json:"PlayedPercentage,omitempty"`
+	UnplayedItemCount     int      `json:"UnplayedItemCount,omitempty"`
+	Rating                *float64 `json:"Rating,omitempty"`
+	Likes                 *bool    `json:"Likes,omitempty"`
+	LastPlayedDate        Time     `json:"LastPlayedDate"`
+	Key                   string   `json:"Key"`
+	ItemID                string   `json:"ItemId"`
+}
+
+// AuthenticationInfo describes an API key.
+type AuthenticationInfo struct {
+	ID               int64  `json:"Id"`
+	AccessToken      string `json:"AccessToken"`
+	DeviceID         string `json:"DeviceId"`
+	AppName          string `json:"AppName"`
+	AppVersion       string `json:"AppVersion"`
+	DeviceName       string `json:"DeviceName"`
+	UserID           string `json:"UserId"`
+	UserName         string `json:"UserName"`
+	IsActive         bool   `json:"IsActive"`
+	DateCreated      Time   `json:"DateCreated"`
+	DateRevoked      Time   `json:"DateRevoked"`
+	DateLastActivity Time   `json:"DateLastActivity"`
+}
+
+// DeviceInfo describes a device that has logged in to the server.
+type DeviceInfo struct {
+	ID               string `json:"Id"`
+	Name             string `json:"Name"`
+	AppName          string `json:"AppName"`
+	AppVersion       string `json:"AppVersion"`
+	LastUserName     string `json:"LastUserName"`
+	LastUserID       string `json:"LastUserId"`
+	DateLastActivity Time   `json:"DateLastActivity"`
+	IconURL          string `json:"IconUrl"`
+	// Jellyfin Only
+	CustomName  string `json:"CustomName"`
+	AccessToken string `json:"AccessToken"`
+	// Emby Only
+	ReportedDeviceID string `json:"ReportedDeviceId"` // The ID given by the client, as seen in SessionInfo.
+	IPAddress        string `json:"IpAddress"`
+}
+
+// DeviceOptions stores the customizable options of a device.
+type DeviceOptions struct {
+	CustomName string `json:"CustomName"`
+}
+
+// ActivityLogEntry is an entry in the server's activity log.
+type ActivityLogEntry struct {
+	ID                  int64  `json:"Id"`
+	Name                string `json:"Name"`
+	Overview            string `json:"Overview"`
+	ShortOverview       string `json:"ShortOverview"`
+	Type                string `json:"Type"` // e.g. "SessionStarted", "AuthenticationFailed", "UserPolicyUpdated".
+	ItemID              string `json:"ItemId"`
+	Date                Time   `json:"Date"`
+	UserID              string `json:"UserId"`
+	UserPrimaryImageTag string `json:"UserPrimaryImageTag"`
+	Severity            string `json:"Severity"`
+}
+
+// ActivityLog is a page of the server's activity log.
+type ActivityLog struct {
+	Items            []ActivityLogEntry `json:"Items"`
+	TotalRecordCount int                `json:"TotalRecordCount"`
+}
+
+// ActivityLogQuery filters and pages the entries returned by GetActivityLog. Zero values are ignored.
+type ActivityLogQuery struct {
+	StartIndex int
+	Limit      int
+	MinDate    time.Time
+	HasUserID  *bool // If set, only return entries with (true) or without (false) a user.
+}
+
+// LibraryUpdateInfo describes changes to the library, sent in a LibraryChanged event.
+type LibraryUpdateInfo struct {
+	FoldersAddedTo     []string `json:"FoldersAddedTo"`
+	FoldersRemovedFrom []string `json:"FoldersRemovedFrom"`
+	ItemsAdded         []string `json:"ItemsAdded"`
+	ItemsRemoved       []string `json:"ItemsRemoved"`
+	ItemsUpdated       []string `json:"ItemsUpdated"`
+	CollectionFolders  []string `json:"CollectionFolders"`
+	IsEmpty            bool     `json:"IsEmpty"`
+}
+
+// TaskResult describes a finished scheduled task, sent in a ScheduledTaskEnded event.
+type TaskResult struct {
+	ID               string `json:"Id"`
+	Key              string `json:"Key"`
+	Name             string `json:"Name"`
+	Status           string `json:"Status"` // "Completed", "Failed", "Cancelled" or "Aborted".
+	StartTimeUtc     Time   `json:"StartTimeUtc"`
+	EndTimeUtc       Time   `json:"EndTimeUtc"`
+	ErrorMessage     string `json:"ErrorMessage"`
+	LongErrorMessage string `json:"LongErrorMessage"`
 }
 
 type AuthenticationResult struct {
@@ -44,6 +277,8 @@ type Configuration struct {
 	RememberSubtitleSelections bool          `json:"RememberSubtitleSelections"`
 	EnableNextEpisodeAutoPlay  bool          `json:"EnableNextEpisodeAutoPlay"`
 	CastReceiverID             string        `json:"CastReceiverId"`
+	// Properties not listed above, kept so they're sent back unchanged by SetConfiguration.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // DeNullConfiguration ensures there are no "null" fields in the given Configuration.
@@ -63,41 +298,62 @@ func DeNullConfiguration(c *Configuration) {
 	}
 }
 
+// UnratedItem is a kind of item without a parental rating, which can be blocked with Policy.BlockUnratedItems.
+type UnratedItem string
+
+const (
+	UnratedMovie          UnratedItem = "Movie"
+	UnratedTrailer        UnratedItem = "Trailer"
+	UnratedSeries         UnratedItem = "Series"
+	UnratedMusic          UnratedItem = "Music"
+	UnratedBook           UnratedItem = "Book"
+	UnratedLiveTvChannel  UnratedItem = "LiveTvChannel"
+	UnratedLiveTvProgram  UnratedItem = "LiveTvProgram"
+	UnratedChannelContent UnratedItem = "ChannelContent"
+	UnratedOther          UnratedItem = "Other"
+)
+
+// ParentalRating is a rating label (e.g. "PG-13") and the value used for it in Policy.MaxParentalRating.
+type ParentalRating struct {
+	Name  string `json:"Name"`
+	Value int    `json:"Value"`
+}
+
 // Policy stores a users permissions.
 type Policy struct {
-	IsAdministrator                  bool          `json:"IsAdministrator"`
-	IsHidden                         bool          `json:"IsHidden"`
-	IsDisabled                       bool          `json:"IsDisabled"`
-	BlockedTags                      []interface{} `json:"BlockedTags,omitempty"`
-	AllowedTags                      []interface{} `json:"AllowedTags"`
-	EnableUserPreferenceAccess       bool          `json:"EnableUserPreferenceAccess"`
-	AccessSchedules                  []interface{} `json:"AccessSchedules,omitempty"`
-	BlockUnratedItems                []interface{} `json:"BlockUnratedItems,omitempty"`
-	EnableRemoteControlOfOtherUsers  bool          `json:"EnableRemoteControlOfOtherUsers"`
-	EnableSharedDeviceControl        bool          `json:"EnableSharedDeviceControl"`
-	EnableRemoteAccess               bool          `json:"EnableRemoteAccess"`
-	EnableLiveTvManagement           bool          `json:"EnableLiveTvManagement"`
-	EnableLiveTvAccess               bool          `json:"EnableLiveTvAccess"`
-	EnableMediaPlayback              bool          `json:"EnableMediaPlayback"`
-	EnableAudioPlaybackTranscoding   bool          `json:"EnableAudioPlaybackTranscoding"`
-	EnableVideoPlaybackTranscoding   bool          `json:"EnableVideoPlaybackTranscoding"`
-	EnablePlaybackRemuxing           bool          `json:"EnablePlaybackRemuxing"`
-	EnableContentDeletion            bool          `json:"EnableContentDeletion"`
-	EnableContentDeletionFromFolders []interface{} `json:"EnableContentDeletionFromFolders,omitempty"`
-	EnableContentDownloading         bool          `json:"EnableContentDownloading"`
-	EnableSyncTranscoding            bool          `json:"EnableSyncTranscoding"`
-	EnableMediaConversion            bool          `json:"EnableMediaConversion"`
-	EnabledDevices                   []interface{} `json:"EnabledDevices,omitempty"`
-	EnableAllDevices                 bool          `json:"EnableAllDevices"`
-	EnabledChannels                  []interface{} `json:"EnabledChannels,omitempty"`
-	EnableAllChannels                bool          `json:"EnableAllChannels"`
-	EnabledFolders                   []string      `json:"EnabledFolders"`
-	EnableAllFolders                 bool          `json:"EnableAllFolders"`
-	InvalidLoginAttemptCount         int           `json:"InvalidLoginAttemptCount"`
-	EnablePublicSharing              bool          `json:"EnablePublicSharing"`
-	RemoteClientBitrateLimit         int           `json:"RemoteClientBitrateLimit"`
-	AuthenticationProviderID         string        `json:"AuthenticationProviderId"`
-	MaxParentalRating                *int          `json:"MaxParentalRating,omitempty"`
+	IsAdministrator                  bool             `json:"IsAdministrator"`
+	IsHidden                         bool             `json:"IsHidden"`
+	IsDisabled                       bool             `json:"IsDisabled"`
+	BlockedTags                      []interface{}    `json:"BlockedTags,omitempty"`
+	AllowedTags                      []interface{}    `json:"AllowedTags"`
+	EnableUserPreferenceAccess       bool             `json:"EnableUserPreferenceAccess"`
+	AccessSchedules                  []AccessSchedule `json:"AccessSchedules,omitempty"`
+	BlockUnratedItems                []UnratedItem    `json:"BlockUnratedItems,omitempty"`
+	EnableRemoteControlOfOtherUsers  bool             `json:"EnableRemoteControlOfOtherUsers"`
+	EnableSharedDeviceControl        bool             `json:"EnableSharedDeviceControl"`
+	EnableRemoteAccess               bool             `json:"EnableRemoteAccess"`
+	EnableLiveTvManagement           bool             `json:"EnableLiveTvManagement"`
+	EnableLiveTvAccess               bool             `json:"EnableLiveTvAccess"`
+	EnableMediaPlayback              bool             `json:"EnableMediaPlayback"`
+	EnableAudioPlaybackTranscoding   bool             `json:"EnableAudioPlaybackTranscoding"`
+	EnableVideoPlaybackTranscoding   bool             `json:"EnableVideoPlaybackTranscoding"`
+	EnablePlaybackRemuxing           bool             `json:"EnablePlaybackRemuxing"`
+	EnableContentDeletion            bool             `json:"EnableContentDeletion"`
+	EnableContentDeletionFromFolders []interface{}    `json:"EnableContentDeletionFromFolders,omitempty"`
+	EnableContentDownloading         bool             `json:"EnableContentDownloading"`
+	EnableSyncTranscoding            bool             `json:"EnableSyncTranscoding"`
+	EnableMediaConversion            bool             `json:"EnableMediaConversion"`
+	EnabledDevices                   []interface{}    `json:"EnabledDevices,omitempty"`
+	EnableAllDevices                 bool             `json:"EnableAllDevices"`
+	EnabledChannels                  []interface{}    `json:"EnabledChannels,omitempty"`
+	EnableAllChannels                bool             `json:"EnableAllChannels"`
+	EnabledFolders                   []string         `json:"EnabledFolders"`
+	EnableAllFolders                 bool             `json:"EnableAllFolders"`
+	InvalidLoginAttemptCount         int              `json:"InvalidLoginAttemptCount"`
+	EnablePublicSharing              bool             `json:"EnablePublicSharing"`
+	RemoteClientBitrateLimit         int              `json:"RemoteClientBitrateLimit"`
+	AuthenticationProviderID         string           `json:"AuthenticationProviderId"`
+	MaxParentalRating                *int             `json:"MaxParentalRating,omitempty"`
 
 	EnableCollectionManagement bool `json:"EnableCollectionManagement"`
 	EnableSubtitleManagement   bool `json:"EnableSubtitleManagement"`
@@ -124,6 +380,8 @@ type Policy struct {
 	IncludeTags                []string      `json:"IncludeTags,omitempty"`
 	RestrictedFeatures         []string      `json:"RestrictedFeatures,omitempty"`
 	LockedOutDate              int64         `json:"LockedOutDate"`
+	// Properties not listed above, kept so they're sent back unchanged by SetPolicy.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // DeNullPolicy ensures there are no "null" fields in the given Policy.
@@ -136,10 +394,10 @@ func DeNullPolicy(p *Policy) {
 		p.AllowedTags = []interface{}{}
 	}
 	if p.AccessSchedules == nil {
-		p.AccessSchedules = []interface{}{}
+		p.AccessSchedules = []AccessSchedule{}
 	}
 	if p.BlockUnratedItems == nil {
-		p.BlockUnratedItems = []interface{}{}
+		p.BlockUnratedItems = []UnratedItem{}
 	}
 	if p.EnableContentDeletionFromFolders == nil {
 		p.EnableContentDeletionFromFolders = []interface{}{}
@@ -188,6 +446,7 @@ type VirtualFolder struct {
 	CollectionType     string         `json:"CollectionType"`
 	LibraryOptions     LibraryOptions `json:"LibraryOptions"`
 	ItemId             string         `json:"ItemId"`
+	Guid               string         `json:"Guid,omitempty"` // Emby only.
 	PrimaryImageItemId string         `json:"PrimaryImageItemId"`
 	RefreshProgress    float64        `json:"RefreshProgress"`
 	RefreshStatus      string         `json:"RefreshStatus"`
@@ -0,0 +1,282 @@
+package mediabrowser
+
+// Iterators over list endpoints, decoding one element at a time from the response rather than loading it all into memory.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Page size used by iterators if none is given.
+const defaultPageSize = 100
+
+// pager streams the elements of a list endpoint. The response can be a paged object, {"Items": [...], "TotalRecordCount": n, ...},
+// in which case further pages are requested until there are no more, or a bare array, which is read in one go.
+// The concrete iterators (ItemIterator etc.) wrap it to give a typed Item method.
+type pager struct {
+	mb  *MediaBrowser
+	ctx context.Context
+	// url returns the address of the page starting at startIndex.
+	url        func(startIndex, limit int) string
+	params     map[string]string // Sent with each request, as with get.
+	limit      int
+	startIndex int
+	body       io.ReadCloser
+	dec        *json.Decoder
+	paged      bool // Whether the current response is a paged object, rather than a bare array.
+	read       int  // Elements read from the current response.
+	total      int  // TotalRecordCount, once known.
+	done       bool // No more responses to request.
+	err        error
+}
+
+func newPager(ctx context.Context, mb *MediaBrowser, startIndex, limit int, url func(startIndex, limit int) string) *pager {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	return &pager{mb: mb, ctx: ctx, url: url, limit: limit, startIndex: startIndex, total: -1}
+}
+
+// next decodes the next element into v, returning false at the end of the list or on error.
+func (p *pager) next(v interface{}) bool {
+	for p.err == nil {
+		if p.dec == nil {
+			if p.done {
+				return false
+			}
+			p.err = p.open()
+			continue
+		}
+		if p.dec.More() {
+			if p.err = p.dec.Decode(v); p.err != nil {
+				break
+			}
+			p.read++
+			return true
+		}
+		if p.err = p.finishPage(); p.err != nil {
+			break
+		}
+	}
+	p.close()
+	return false
+}
+
+// open requests the next page, leaving the decoder positioned at the start of the list's elements.
+func (p *pager) open() error {
+	body, data, status, err := p.mb.getStream(p.ctx, p.url(p.startIndex, p.limit), p.params)
+	if customErr := p.mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return err
+	}
+	p.body, p.dec, p.read = body, json.NewDecoder(body), 0
+	tok, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('['):
+		p.paged = false
+		return nil
+	case json.Delim('{'):
+		p.paged = true
+	default:
+		return fmt.Errorf("unexpected %v at start of response", tok)
+	}
+	// Skip to the Items array, noting TotalRecordCount if it's first.
+	for p.dec.More() {
+		key, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		if key == "Items" {
+			tok, err := p.dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				// "Items": null, so end the page here.
+				return p.skipRest()
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("unexpected %v for Items", tok)
+			}
+			return nil
+		}
+		if err := p.decodeField(key); err != nil {
+			return err
+		}
+	}
+	// No Items at all.
+	return p.skipRest()
+}
+
+// skipRest reads the rest of a paged object without an Items array, leaving nothing for next to decode.
+func (p *pager) skipRest() error {
+	if err := p.readFields(); err != nil {
+		return err
+	}
+	p.finishResponse()
+	return nil
+}
+
+func (p *pager) decodeField(key json.Token) error {
+	if key == "TotalRecordCount" {
+		return p.dec.Decode(&p.total)
+	}
+	var skip json.RawMessage
+	return p.dec.Decode(&skip)
+}
+
+// readFields reads the remaining fields of the paged object, noting TotalRecordCount, and its closing brace.
+func (p *pager) readFields() error {
+	for p.dec.More() {
+		key, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := p.decodeField(key); err != nil {
+			return err
+		}
+	}
+	_, err := p.dec.Token()
+	return err
+}
+
+// finishPage reads the end of the list (and the rest of the object, if paged), then works out whether there's another page.
+func (p *pager) finishPage() error {
+	if _, err := p.dec.Token(); err != nil {
+		return err
+	}
+	if p.paged {
+		if err := p.readFields(); err != nil {
+			return err
+		}
+	}
+	p.finishResponse()
+	return nil
+}
+
+// finishResponse closes the current response and decides whether to request another.
+func (p *pager) finishResponse() {
+	p.close()
+	p.startIndex += p.read
+	switch {
+	case !p.paged, p.read == 0:
+		// An empty page would only be followed by the same one.
+		p.done = true
+	case p.total >= 0:
+		// Servers may send fewer than asked for (e.g. capping the limit), so the total decides.
+		p.done = p.startIndex >= p.total
+	default:
+		p.done = p.read < p.limit
+	}
+}
+
+func (p *pager) close() {
+	if p.body != nil {
+		p.body.Close()
+	}
+	p.body, p.dec = nil, nil
+}
+
+// ItemIterator iterates over the results of an items query, see MediaBrowser.ItemIterator.
+type ItemIterator struct {
+	p    *pager
+	item BaseItem
+}
+
+// ItemIterator returns an iterator over every item matching the query, starting at query.StartIndex and fetching query.Limit (default 100) at a time.
+// Use like:
+//
+//	it := mb.ItemIterator(ItemsQuery{Recursive: true})
+//	defer it.Close()
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	if it.Err() != nil { ... }
+func (mb *MediaBrowser) ItemIterator(query ItemsQuery) *ItemIterator {
+	return mb.ItemIteratorContext(context.Background(), query)
+}
+
+// ItemIteratorContext is ItemIterator with a context controlling the requests.
+func (mb *MediaBrowser) ItemIteratorContext(ctx context.Context, query ItemsQuery) *ItemIterator {
+	return &ItemIterator{p: newPager(ctx, mb, query.StartIndex, query.Limit, func(startIndex, limit int) string {
+		query.StartIndex, query.Limit = startIndex, limit
+		return mb.itemsURL(query)
+	})}
+}
+
+// Next advances to the next item, fetching the next page if necessary. It returns false when there are no more items, or an error occurred.
+func (it *ItemIterator) Next() bool {
+	it.item = BaseItem{}
+	return it.p.next(&it.item)
+}
+
+// Item returns the current item.
+func (it *ItemIterator) Item() BaseItem {
+	return it.item
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *ItemIterator) Err() error {
+	return it.p.err
+}
+
+// Close releases the current response. Only needed if you stop before Next returns false.
+func (it *ItemIterator) Close() {
+	it.p.close()
+	it.p.done = true
+}
+
+// UserIterator iterates over users, see MediaBrowser.UserIterator.
+type UserIterator struct {
+	p    *pager
+	user User
+}
+
+// UserIterator returns an iterator over all users on the server, decoded one at a time, bypassing the user cache.
+// The servers don't page the user list, so it's fetched in a single request.
+func (mb *MediaBrowser) UserIterator() *UserIterator {
+	return mb.UserIteratorContext(context.Background())
+}
+
+// UserIteratorContext is UserIterator with a context controlling the requests.
+func (mb *MediaBrowser) UserIteratorContext(ctx context.Context) *UserIterator {
+	it := &UserIterator{p: newPager(ctx, mb, 0, 0, func(startIndex, limit int) string {
+		return fmt.Sprintf("%s/users", mb.Server)
+	})}
+	// As with GetUsers.
+	if err := mb.ensureAuthenticated(ctx); err != nil {
+		it.p.err = err
+		return it
+	}
+	_, it.p.params = mb.credentials()
+	return it
+}
+
+// Next advances to the next user. It returns false when there are no more users, or an error occurred.
+func (it *UserIterator) Next() bool {
+	it.user = User{}
+	return it.p.next(&it.user)
+}
+
+// Item returns the current user.
+func (it *UserIterator) Item() User {
+	return it.user
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *UserIterator) Err() error {
+	return it.p.err
+}
+
+// Close releases the response. Only needed if you stop before Next returns false.
+func (it *UserIterator) Close() {
+	it.p.close()
+	it.p.done = true
+}
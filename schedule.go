@@ -0,0 +1,115 @@
+package mediabrowser
+
+import (
+	"sort"
+	"time"
+)
+
+// ScheduleDay is the day (or group of days) an AccessSchedule applies to.
+type ScheduleDay string
+
+const (
+	ScheduleSunday    ScheduleDay = "Sunday"
+	ScheduleMonday    ScheduleDay = "Monday"
+	ScheduleTuesday   ScheduleDay = "Tuesday"
+	ScheduleWednesday ScheduleDay = "Wednesday"
+	ScheduleThursday  ScheduleDay = "Thursday"
+	ScheduleFriday    ScheduleDay = "Friday"
+	ScheduleSaturday  ScheduleDay = "Saturday"
+	ScheduleEveryday  ScheduleDay = "Everyday"
+	ScheduleWeekday   ScheduleDay = "Weekday" // Monday to Friday.
+	ScheduleWeekend   ScheduleDay = "Weekend" // Saturday & Sunday.
+)
+
+// Includes returns whether the given day is one of those covered by d.
+func (d ScheduleDay) Includes(day time.Weekday) bool {
+	switch d {
+	case ScheduleEveryday:
+		return true
+	case ScheduleWeekday:
+		return day != time.Saturday && day != time.Sunday
+	case ScheduleWeekend:
+		return day == time.Saturday || day == time.Sunday
+	}
+	return string(d) == day.String()
+}
+
+// AccessSchedule is a window during which a user is allowed to use the server. Hours are in the server's local time, from 0 to 24,
+// and can be fractional (e.g. 17.5 is 17:30). Both ends are inclusive.
+type AccessSchedule struct {
+	ID        int         `json:"Id,omitempty"`     // Jellyfin only, assigned by the server.
+	UserID    string      `json:"UserId,omitempty"` // Jellyfin only, assigned by the server.
+	DayOfWeek ScheduleDay `json:"DayOfWeek"`
+	StartHour float64     `json:"StartHour"`
+	EndHour   float64     `json:"EndHour"`
+}
+
+// window returns the start and end of the schedule on the day starting at midnight.
+func (s AccessSchedule) window(midnight time.Time) (start, end time.Time) {
+	hoursToTime := func(hours float64) time.Time {
+		// Built from the date rather than adding a duration to midnight, so a DST change doesn't shift it.
+		minutes := int(hours*60 + 0.5)
+		return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, minutes, 0, 0, midnight.Location())
+	}
+	return hoursToTime(s.StartHour), hoursToTime(s.EndHour)
+}
+
+// Contains returns whether t falls within the schedule, in t's location.
+func (s AccessSchedule) Contains(t time.Time) bool {
+	if !s.DayOfWeek.Includes(t.Weekday()) {
+		return false
+	}
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	return hour >= s.StartHour && hour <= s.EndHour
+}
+
+// AllowedAt returns whether the policy's AccessSchedules allow access at t, with the server in the given timezone (nil for UTC).
+// As on the server, a policy with no schedules is always allowed.
+func (p Policy) AllowedAt(t time.Time, serverLocation *time.Location) bool {
+	if len(p.AccessSchedules) == 0 {
+		return true
+	}
+	if serverLocation == nil {
+		serverLocation = time.UTC
+	}
+	t = t.In(serverLocation)
+	for _, s := range p.AccessSchedules {
+		if s.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAccessWindow returns the window of access containing or following t, with the server in the given timezone (nil for UTC).
+// If access is currently allowed, start is at or before t. ok is false if there are no schedules (so access is never restricted),
+// or none cover any day in the following week. Overlapping schedules aren't merged, so end may be earlier than when access is really lost.
+func (p Policy) NextAccessWindow(t time.Time, serverLocation *time.Location) (start, end time.Time, ok bool) {
+	if len(p.AccessSchedules) == 0 {
+		return
+	}
+	if serverLocation == nil {
+		serverLocation = time.UTC
+	}
+	t = t.In(serverLocation)
+	type window struct{ start, end time.Time }
+	var windows []window
+	// Checking 8 days covers a schedule for today that's already ended and recurs next week.
+	for offset := 0; offset <= 7; offset++ {
+		midnight := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, serverLocation)
+		for _, s := range p.AccessSchedules {
+			if !s.DayOfWeek.Includes(midnight.Weekday()) || s.EndHour < s.StartHour {
+				continue
+			}
+			start, end := s.window(midnight)
+			if !end.Before(t) {
+				windows = append(windows, window{start, end})
+			}
+		}
+	}
+	if len(windows) == 0 {
+		return
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start.Before(windows[j].start) })
+	return windows[0].start, windows[0].end, true
+}
@@ -0,0 +1,65 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAccessSchedules(t *testing.T) {
+	var p Policy
+	err := json.Unmarshal([]byte(`{"AccessSchedules":[
+		{"Id":1,"UserId":"aaaaaaaaaaaa","DayOfWeek":"Weekday","StartHour":17,"EndHour":21.5},
+		{"DayOfWeek":"Saturday","StartHour":9,"EndHour":24}
+	]}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	at := func(day, hour, minute int) time.Time {
+		// 2024-01-01 was a Monday.
+		return time.Date(2024, 1, day, hour, minute, 0, 0, loc)
+	}
+	allowed := []struct {
+		t    time.Time
+		want bool
+	}{
+		{at(1, 16, 59), false},
+		{at(1, 17, 0), true},
+		{at(3, 21, 30), true},
+		{at(3, 21, 31), false},
+		{at(6, 8, 0), false},  // Saturday
+		{at(6, 23, 59), true}, // Saturday
+		{at(7, 18, 0), false}, // Sunday
+	}
+	for _, c := range allowed {
+		if got := p.AllowedAt(c.t, loc); got != c.want {
+			t.Errorf("AllowedAt(%s) = %t, want %t", c.t.Format(time.RFC1123), got, c.want)
+		}
+	}
+	// The server's timezone applies, not the caller's.
+	if !p.AllowedAt(at(1, 17, 0).UTC(), loc) {
+		t.Errorf("AllowedAt ignored the server's location")
+	}
+
+	windows := []struct {
+		t, start, end time.Time
+	}{
+		{at(1, 12, 0), at(1, 17, 0), at(1, 21, 30)},
+		{at(1, 18, 0), at(1, 17, 0), at(1, 21, 30)},
+		{at(5, 22, 0), at(6, 9, 0), at(7, 0, 0)},
+		{at(7, 12, 0), at(8, 17, 0), at(8, 21, 30)},
+	}
+	for _, c := range windows {
+		start, end, ok := p.NextAccessWindow(c.t, loc)
+		if !ok || !start.Equal(c.start) || !end.Equal(c.end) {
+			t.Errorf("NextAccessWindow(%s) = %s - %s (%t), want %s - %s", c.t.Format(time.RFC1123), start, end, ok, c.start, c.end)
+		}
+	}
+	if _, _, ok := (Policy{}).NextAccessWindow(at(1, 0, 0), loc); ok {
+		t.Errorf("expected no window for an unrestricted policy")
+	}
+	if !(Policy{}).AllowedAt(at(1, 0, 0), loc) {
+		t.Errorf("expected an unrestricted policy to be allowed")
+	}
+}
@@ -0,0 +1,18 @@
+package mediabrowser
+
+import "context"
+
+// Like on Jellyfin, we log the session out by deleting its device.
+// Emby's device IDs differ from those reported by the client (as in SessionInfo), so we have to find it first.
+func embyEndSession(ctx context.Context, emby *MediaBrowser, session SessionInfo) error {
+	devices, err := emby.GetDevicesContext(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if device.ReportedDeviceID == session.DeviceID {
+			return emby.DeleteDeviceContext(ctx, device.ID)
+		}
+	}
+	return NotFound
+}
@@ -0,0 +1,8 @@
+package mediabrowser
+
+import "context"
+
+// Deleting the session's device revokes its access token, logging it out.
+func jfEndSession(ctx context.Context, jf *MediaBrowser, session SessionInfo) error {
+	return jf.DeleteDeviceContext(ctx, session.DeviceID)
+}
@@ -0,0 +1,203 @@
+package mediabrowser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetSessions returns the sessions on the server matching the given filter.
+func (mb *MediaBrowser) GetSessions(filter SessionFilter) ([]SessionInfo, error) {
+	return mb.GetSessionsContext(context.Background(), filter)
+}
+
+// GetSessionsContext is GetSessions with a context controlling the request.
+func (mb *MediaBrowser) GetSessionsContext(ctx context.Context, filter SessionFilter) ([]SessionInfo, error) {
+	query := url.Values{}
+	if filter.ControllableByUserID != "" {
+		query.Set("controllableByUserId", filter.ControllableByUserID)
+	}
+	if filter.DeviceID != "" {
+		query.Set("deviceId", filter.DeviceID)
+	}
+	if filter.ActiveWithinSeconds != 0 {
+		query.Set("activeWithinSeconds", strconv.Itoa(filter.ActiveWithinSeconds))
+	}
+	u := fmt.Sprintf("%s/Sessions", mb.Server)
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	var sessions []SessionInfo
+	data, status, err := mb.getJSON(ctx, u, nil, &sessions, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// LogoutSession ends this MediaBrowser's own session, invalidating its access token.
+func (mb *MediaBrowser) LogoutSession() error {
+	return mb.LogoutSessionContext(context.Background())
+}
+
+// LogoutSessionContext is LogoutSession with a context controlling the request.
+func (mb *MediaBrowser) LogoutSessionContext(ctx context.Context) error {
+	url := fmt.Sprintf("%s/Sessions/Logout", mb.Server)
+	_, status, err := mb.post(ctx, url, nil, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err == nil {
+		mb.authLock.Lock()
+		mb.Authenticated = false
+		mb.authLock.Unlock()
+	}
+	return err
+}
+
+// EndUserSessions ends every session belonging to the given user, returning the number ended.
+// Useful for logging a user out everywhere after disabling their account.
+func (mb *MediaBrowser) EndUserSessions(userID string) (int, error) {
+	return mb.EndUserSessionsContext(context.Background(), userID)
+}
+
+// EndUserSessionsContext is EndUserSessions with a context controlling the requests.
+func (mb *MediaBrowser) EndUserSessionsContext(ctx context.Context, userID string) (int, error) {
+	sessions, err := mb.GetSessionsContext(ctx, SessionFilter{})
+	if err != nil {
+		return 0, err
+	}
+	ended := 0
+	for _, session := range sessions {
+		if session.UserID != userID {
+			continue
+		}
+		if err := mb.endSession(ctx, session); err != nil {
+			return ended, err
+		}
+		ended++
+	}
+	return ended, nil
+}
+
+// SendMessage displays a message on the given session's client.
+// If timeout is zero, the message stays until the user dismisses it.
+func (mb *MediaBrowser) SendMessage(sessionID, header, text string, timeout time.Duration) error {
+	return mb.SendMessageContext(context.Background(), sessionID, header, text, timeout)
+}
+
+// SendMessageContext is SendMessage with a context controlling the request.
+func (mb *MediaBrowser) SendMessageContext(ctx context.Context, sessionID, header, text string, timeout time.Duration) error {
+	u := fmt.Sprintf("%s/Sessions/%s/Message", mb.Server, url.PathEscape(sessionID))
+	_, status, err := mb.post(ctx, u, messageCommand{
+		Header:    header,
+		Text:      text,
+		TimeoutMs: timeout.Milliseconds(),
+	}, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
+
+// MessageUserSessions displays a message on every session belonging to the given user, returning the number messaged.
+// Useful for warning a user before their account is disabled.
+func (mb *MediaBrowser) MessageUserSessions(userID, header, text string, timeout time.Duration) (int, error) {
+	return mb.MessageUserSessionsContext(context.Background(), userID, header, text, timeout)
+}
+
+// MessageUserSessionsContext is MessageUserSessions with a context controlling the requests.
+func (mb *MediaBrowser) MessageUserSessionsContext(ctx context.Context, userID, header, text string, timeout time.Duration) (int, error) {
+	sessions, err := mb.GetSessionsContext(ctx, SessionFilter{})
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, session := range sessions {
+		if session.UserID != userID {
+			continue
+		}
+		if err := mb.SendMessageContext(ctx, session.ID, header, text, timeout); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// SendPlaystateCommand controls playback on the given session. seekPositionTicks is only used by PlaystateSeek.
+func (mb *MediaBrowser) SendPlaystateCommand(sessionID string, command PlaystateCommand, seekPositionTicks int64) error {
+	return mb.SendPlaystateCommandContext(context.Background(), sessionID, command, seekPositionTicks)
+}
+
+// SendPlaystateCommandContext is SendPlaystateCommand with a context controlling the request.
+func (mb *MediaBrowser) SendPlaystateCommandContext(ctx context.Context, sessionID string, command PlaystateCommand, seekPositionTicks int64) error {
+	u := fmt.Sprintf("%s/Sessions/%s/Playing/%s", mb.Server, url.PathEscape(sessionID), command)
+	if command == PlaystateSeek {
+		u += "?seekPositionTicks=" + strconv.FormatInt(seekPositionTicks, 10)
+	}
+	_, status, err := mb.post(ctx, u, nil, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
+
+// SendGeneralCommand sends a general command (e.g. CommandGoHome) to the given session.
+func (mb *MediaBrowser) SendGeneralCommand(sessionID string, command GeneralCommand) error {
+	return mb.SendGeneralCommandContext(context.Background(), sessionID, command)
+}
+
+// SendGeneralCommandContext is SendGeneralCommand with a context controlling the request.
+func (mb *MediaBrowser) SendGeneralCommandContext(ctx context.Context, sessionID string, command GeneralCommand) error {
+	u := fmt.Sprintf("%s/Sessions/%s/Command", mb.Server, url.PathEscape(sessionID))
+	_, status, err := mb.post(ctx, u, command, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
+
+// DisplayContent tells the given session's client to navigate to an item.
+func (mb *MediaBrowser) DisplayContent(sessionID, itemID, itemType, itemName string) error {
+	return mb.DisplayContentContext(context.Background(), sessionID, itemID, itemType, itemName)
+}
+
+// DisplayContentContext is DisplayContent with a context controlling the request.
+func (mb *MediaBrowser) DisplayContentContext(ctx context.Context, sessionID, itemID, itemType, itemName string) error {
+	return mb.SendGeneralCommandContext(ctx, sessionID, GeneralCommand{
+		Name: CommandDisplayContent,
+		Arguments: map[string]string{
+			"ItemId":   itemID,
+			"ItemType": itemType,
+			"ItemName": itemName,
+		},
+	})
+}
+
+// PlayNow starts playback of the given items on the session, replacing its queue.
+func (mb *MediaBrowser) PlayNow(sessionID string, itemIDs []string, startPositionTicks int64) error {
+	return mb.PlayNowContext(context.Background(), sessionID, itemIDs, startPositionTicks)
+}
+
+// PlayNowContext is PlayNow with a context controlling the request.
+func (mb *MediaBrowser) PlayNowContext(ctx context.Context, sessionID string, itemIDs []string, startPositionTicks int64) error {
+	query := url.Values{}
+	query.Set("playCommand", "PlayNow")
+	query.Set("itemIds", strings.Join(itemIDs, ","))
+	if startPositionTicks != 0 {
+		query.Set("startPositionTicks", strconv.FormatInt(startPositionTicks, 10))
+	}
+	u := fmt.Sprintf("%s/Sessions/%s/Playing?%s", mb.Server, url.PathEscape(sessionID), query.Encode())
+	_, status, err := mb.post(ctx, u, nil, false)
+	if customErr := mb.genericErr(status, ""); err == nil && customErr != nil {
+		err = customErr
+	}
+	return err
+}
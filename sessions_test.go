@@ -0,0 +1,114 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEndUserSessions(t *testing.T) {
+	deleted := map[string]bool{}
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Sessions":
+			if got := r.URL.Query().Get("activeWithinSeconds"); got != "" {
+				t.Errorf("unexpected filter activeWithinSeconds=%s", got)
+			}
+			w.Write([]byte(`[
+				{"Id":"s1","UserId":"u1","DeviceId":"d1","Client":"Jellyfin Web","LastActivityDate":"2024-03-01T10:00:00.0000000Z",
+				 "NowPlayingItem":{"Id":"i1","Name":"Film","Type":"Movie","RunTimeTicks":72000000000},
+				 "PlayState":{"PositionTicks":600000000,"IsPaused":true},"TranscodingInfo":{"VideoCodec":"h264","IsVideoDirect":false}},
+				{"Id":"s2","UserId":"u2","DeviceId":"d2"},
+				{"Id":"s3","UserId":"u1","DeviceId":"d3","LastActivityDate":null}
+			]`))
+		case "/Devices":
+			if r.Method != "DELETE" {
+				t.Errorf("unexpected method %s", r.Method)
+			}
+			deleted[r.URL.Query().Get("id")] = true
+			w.WriteHeader(204)
+		}
+	}))
+
+	sessions, err := mb.GetSessions(SessionFilter{})
+	if err != nil {
+		t.Fatalf("GetSessions failed: %v", err)
+	}
+	if len(sessions) != 3 || sessions[0].NowPlayingItem == nil || sessions[0].NowPlayingItem.Name != "Film" || !sessions[0].PlayState.IsPaused || sessions[0].TranscodingInfo.VideoCodec != "h264" {
+		t.Fatalf("sessions decoded incorrectly: %+v", sessions)
+	}
+	if sessions[0].LastActivityDate.Year() != 2024 {
+		t.Errorf("LastActivityDate decoded incorrectly: %v", sessions[0].LastActivityDate)
+	}
+
+	n, err := mb.EndUserSessions("u1")
+	if err != nil {
+		t.Fatalf("EndUserSessions failed: %v", err)
+	}
+	if n != 2 || !deleted["d1"] || !deleted["d3"] || deleted["d2"] {
+		t.Errorf("wrong sessions ended (%d): %v", n, deleted)
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	var got []string
+	var message messageCommand
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/System/Info/Public" {
+			return
+		}
+		got = append(got, r.Method+" "+r.URL.RequestURI())
+		if r.URL.Path == "/Sessions/s1/Message" {
+			json.NewDecoder(r.Body).Decode(&message)
+		}
+		w.WriteHeader(204)
+	}))
+
+	if err := mb.SendMessage("s1", "Account expiry", "Your account expires tomorrow.", 10*time.Second); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if message.Header != "Account expiry" || message.TimeoutMs != 10000 {
+		t.Errorf("message sent incorrectly: %+v", message)
+	}
+	if err := mb.SendPlaystateCommand("s1", PlaystateSeek, 1000); err != nil {
+		t.Fatalf("SendPlaystateCommand failed: %v", err)
+	}
+	if err := mb.PlayNow("s1", []string{"a", "b"}, 0); err != nil {
+		t.Fatalf("PlayNow failed: %v", err)
+	}
+	expected := []string{
+		"POST /Sessions/s1/Message",
+		"POST /Sessions/s1/Playing/Seek?seekPositionTicks=1000",
+		"POST /Sessions/s1/Playing?itemIds=a%2Cb&playCommand=PlayNow",
+	}
+	for i := range expected {
+		if i >= len(got) || got[i] != expected[i] {
+			t.Fatalf("requests were %v, expected %v", got, expected)
+		}
+	}
+}
+
+func TestEmbyEndSession(t *testing.T) {
+	deleted := ""
+	mb, _ := newTestServer(t, EmbyServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/Sessions":
+			w.Write([]byte(`[{"Id":"s1","UserId":"u1","DeviceId":"reported-id"}]`))
+		case r.URL.Path == "/Devices" && r.Method == "GET":
+			w.Write([]byte(`{"Items":[{"Id":"7","ReportedDeviceId":"other-id"},{"Id":"8","ReportedDeviceId":"reported-id","DateLastActivity":"2024-03-01T10:00:00.0000000Z"}],"TotalRecordCount":2}`))
+		case r.URL.Path == "/Devices" && r.Method == "DELETE":
+			deleted = r.URL.Query().Get("id")
+			w.WriteHeader(204)
+		}
+	}))
+	if err := mb.EndSession("s1"); err != nil {
+		t.Fatalf("EndSession failed: %v", err)
+	}
+	if deleted != "8" {
+		t.Errorf("deleted device %q, expected \"8\"", deleted)
+	}
+	if err := mb.EndSession("s2"); err != NotFound {
+		t.Errorf("expected NotFound for a missing session, got %v", err)
+	}
+}
@@ -12,6 +12,10 @@ type Time struct {
 }
 
 func (t *Time) UnmarshalJSON(b []byte) (err error) {
+	// Nullable dates are left as the zero value.
+	if string(b) == "null" {
+		return nil
+	}
 	// Trim quotes from beginning and end, and any number of Zs (indicates UTC).
 	for b[0] == '"' {
 		b = b[1:]
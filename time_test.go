@@ -23,6 +23,10 @@ func TestUnmarshalJSON(t *testing.T) {
 			t.Errorf("%s parsed incorrectly to %v, should have been %v", in, parsed.Time, expected)
 		}
 	}
+	parsed := Time{}
+	if err := parsed.UnmarshalJSON([]byte("null")); err != nil || !parsed.IsZero() {
+		t.Errorf("null parsed incorrectly to %v (err %v), should have been zero", parsed.Time, err)
+	}
 }
 
 func benchUnmarshalJSON(tests []string, b *testing.B) {
@@ -0,0 +1,153 @@
+package mediabrowser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GetUserItemData returns the user's data (played state, favorite, position etc.) for the given item.
+func (mb *MediaBrowser) GetUserItemData(userID, itemID string) (UserItemData, error) {
+	return mb.GetUserItemDataContext(context.Background(), userID, itemID)
+}
+
+// GetUserItemDataContext is GetUserItemData with a context controlling the request.
+func (mb *MediaBrowser) GetUserItemDataContext(ctx context.Context, userID, itemID string) (UserItemData, error) {
+	url := fmt.Sprintf("%s/Users/%s/Items/%s", mb.Server, userID, itemID)
+	var item BaseItem
+	data, status, err := mb.getJSON(ctx, url, nil, &item, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return UserItemData{}, err
+	}
+	if item.UserData == nil {
+		return UserItemData{ItemID: itemID}, nil
+	}
+	return *item.UserData, nil
+}
+
+// userItemDataResponse handles the response of a request changing user data, which both servers answer with the new UserItemData.
+// If the body is missing (e.g. a 204), the data is fetched instead.
+func (mb *MediaBrowser) userItemDataResponse(ctx context.Context, userID, itemID, data string, status int, err error) (UserItemData, error) {
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err != nil {
+		return UserItemData{}, err
+	}
+	var result UserItemData
+	if data == "" || json.Unmarshal([]byte(data), &result) != nil {
+		return mb.GetUserItemDataContext(ctx, userID, itemID)
+	}
+	return result, nil
+}
+
+// SetPlayed marks the item as played or unplayed by the user.
+func (mb *MediaBrowser) SetPlayed(userID, itemID string, played bool) (UserItemData, error) {
+	return mb.SetPlayedContext(context.Background(), userID, itemID, played)
+}
+
+// SetPlayedContext is SetPlayed with a context controlling the request.
+func (mb *MediaBrowser) SetPlayedContext(ctx context.Context, userID, itemID string, played bool) (UserItemData, error) {
+	url := fmt.Sprintf("%s/Users/%s/PlayedItems/%s", mb.Server, userID, itemID)
+	var data string
+	var status int
+	var err error
+	if played {
+		data, status, err = mb.post(ctx, url, nil, true)
+	} else {
+		data, status, err = mb.delete(ctx, url)
+	}
+	return mb.userItemDataResponse(ctx, userID, itemID, data, status, err)
+}
+
+// SetFavorite adds the item to or removes it from the user's favorites.
+func (mb *MediaBrowser) SetFavorite(userID, itemID string, favorite bool) (UserItemData, error) {
+	return mb.SetFavoriteContext(context.Background(), userID, itemID, favorite)
+}
+
+// SetFavoriteContext is SetFavorite with a context controlling the request.
+func (mb *MediaBrowser) SetFavoriteContext(ctx context.Context, userID, itemID string, favorite bool) (UserItemData, error) {
+	url := fmt.Sprintf("%s/Users/%s/FavoriteItems/%s", mb.Server, userID, itemID)
+	var data string
+	var status int
+	var err error
+	if favorite {
+		data, status, err = mb.post(ctx, url, nil, true)
+	} else {
+		data, status, err = mb.delete(ctx, url)
+	}
+	return mb.userItemDataResponse(ctx, userID, itemID, data, status, err)
+}
+
+// SetLikes sets whether the user likes (true) or dislikes (false) the item. nil removes the rating.
+func (mb *MediaBrowser) SetLikes(userID, itemID string, likes *bool) (UserItemData, error) {
+	return mb.SetLikesContext(context.Background(), userID, itemID, likes)
+}
+
+// SetLikesContext is SetLikes with a context controlling the request.
+func (mb *MediaBrowser) SetLikesContext(ctx context.Context, userID, itemID string, likes *bool) (UserItemData, error) {
+	url := fmt.Sprintf("%s/Users/%s/Items/%s/Rating", mb.Server, userID, itemID)
+	var data string
+	var status int
+	var err error
+	if likes != nil {
+		data, status, err = mb.post(ctx, fmt.Sprintf("%s?likes=%t", url, *likes), nil, true)
+	} else {
+		data, status, err = mb.delete(ctx, url)
+	}
+	return mb.userItemDataResponse(ctx, userID, itemID, data, status, err)
+}
+
+// userItemDataUpdate is the body sent to update user data. Unlike UserItemData, an unset LastPlayedDate is left out rather than sent as year 1.
+type userItemDataUpdate struct {
+	PlaybackPositionTicks int64      `json:"PlaybackPositionTicks"`
+	PlayCount             int        `json:"PlayCount"`
+	IsFavorite            bool       `json:"IsFavorite"`
+	Played                bool       `json:"Played"`
+	Rating                *float64   `json:"Rating,omitempty"`
+	Likes                 *bool      `json:"Likes,omitempty"`
+	LastPlayedDate        *time.Time `json:"LastPlayedDate,omitempty"`
+}
+
+// SetUserItemData overwrites the user's data for the item with the given PlaybackPositionTicks, PlayCount, IsFavorite, Played, Rating, Likes & LastPlayedDate.
+// Useful for restoring a user's state, e.g. to a recreated account.
+func (mb *MediaBrowser) SetUserItemData(userID, itemID string, userData UserItemData) (UserItemData, error) {
+	return mb.SetUserItemDataContext(context.Background(), userID, itemID, userData)
+}
+
+// SetUserItemDataContext is SetUserItemData with a context controlling the request.
+func (mb *MediaBrowser) SetUserItemDataContext(ctx context.Context, userID, itemID string, userData UserItemData) (UserItemData, error) {
+	url := fmt.Sprintf("%s/Users/%s/Items/%s/UserData", mb.Server, userID, itemID)
+	update := userItemDataUpdate{
+		PlaybackPositionTicks: userData.PlaybackPositionTicks,
+		PlayCount:             userData.PlayCount,
+		IsFavorite:            userData.IsFavorite,
+		Played:                userData.Played,
+		Rating:                userData.Rating,
+		Likes:                 userData.Likes,
+	}
+	if !userData.LastPlayedDate.IsZero() {
+		update.LastPlayedDate = &userData.LastPlayedDate.Time
+	}
+	data, status, err := mb.post(ctx, url, update, true)
+	return mb.userItemDataResponse(ctx, userID, itemID, data, status, err)
+}
+
+// SetPlaybackPosition sets where the user will resume the item from, leaving the rest of their data for it unchanged.
+func (mb *MediaBrowser) SetPlaybackPosition(userID, itemID string, ticks int64) (UserItemData, error) {
+	return mb.SetPlaybackPositionContext(context.Background(), userID, itemID, ticks)
+}
+
+// SetPlaybackPositionContext is SetPlaybackPosition with a context controlling the requests.
+func (mb *MediaBrowser) SetPlaybackPositionContext(ctx context.Context, userID, itemID string, ticks int64) (UserItemData, error) {
+	userData, err := mb.GetUserItemDataContext(ctx, userID, itemID)
+	if err != nil {
+		return UserItemData{}, err
+	}
+	userData.PlaybackPositionTicks = ticks
+	return mb.SetUserItemDataContext(ctx, userID, itemID, userData)
+}
@@ -0,0 +1,70 @@
+package mediabrowser
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUserItemData(t *testing.T) {
+	var requests []string
+	var posted map[string]interface{}
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/System/Info/Public" {
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/Users/aaaaaaaaaaaa/Items/item1":
+			w.Write([]byte(`{"Id":"item1","UserData":{"PlaybackPositionTicks":5,"PlayCount":3,"Played":true,"IsFavorite":true,"LastPlayedDate":"2024-03-01T20:00:00.0000000Z"}}`))
+		case "/Users/aaaaaaaaaaaa/PlayedItems/item1":
+			w.Write([]byte(`{"Played":true,"PlayCount":4,"ItemId":"item1"}`))
+		case "/Users/aaaaaaaaaaaa/Items/item1/UserData":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+
+	data, err := mb.SetPlayed("aaaaaaaaaaaa", "item1", true)
+	if err != nil || !data.Played || data.PlayCount != 4 {
+		t.Errorf("SetPlayed = %+v, %v", data, err)
+	}
+	// No body, so the data is fetched instead.
+	likes := true
+	data, err = mb.SetLikes("aaaaaaaaaaaa", "item1", &likes)
+	if err != nil || data.PlayCount != 3 {
+		t.Errorf("SetLikes = %+v, %v", data, err)
+	}
+	mb.SetFavorite("aaaaaaaaaaaa", "item1", false)
+	want := []string{
+		"POST /Users/aaaaaaaaaaaa/PlayedItems/item1",
+		"POST /Users/aaaaaaaaaaaa/Items/item1/Rating?likes=true",
+		"GET /Users/aaaaaaaaaaaa/Items/item1",
+		"DELETE /Users/aaaaaaaaaaaa/FavoriteItems/item1",
+	}
+	for i := range want {
+		if i >= len(requests) || requests[i] != want[i] {
+			t.Fatalf("unexpected requests:\n got %v\nwant %v", requests, want)
+		}
+	}
+
+	if _, err := mb.SetPlaybackPosition("aaaaaaaaaaaa", "item1", 1234); err != nil {
+		t.Fatalf("SetPlaybackPosition failed: %v", err)
+	}
+	if posted["PlaybackPositionTicks"] != 1234.0 || posted["PlayCount"] != 3.0 || posted["IsFavorite"] != true {
+		t.Errorf("other data not preserved: %v", posted)
+	}
+	if last, _ := time.Parse(time.RFC3339, posted["LastPlayedDate"].(string)); last.Month() != time.March {
+		t.Errorf("unexpected LastPlayedDate: %v", posted["LastPlayedDate"])
+	}
+
+	posted = nil
+	mb.SetUserItemData("aaaaaaaaaaaa", "item1", UserItemData{Played: true})
+	if _, ok := posted["LastPlayedDate"]; ok {
+		t.Errorf("zero LastPlayedDate sent: %v", posted)
+	}
+}
@@ -3,23 +3,17 @@ package mediabrowser
 // Almost identical to jfapi, with the most notable change being the password workaround.
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 )
 
-func embyDeleteUser(emby *MediaBrowser, userID string) error {
+func embyDeleteUser(ctx context.Context, emby *MediaBrowser, userID string) error {
 	url := fmt.Sprintf("%s/Users/%s", emby.Server, userID)
-	req, _ := http.NewRequest("DELETE", url, nil)
-	for name, value := range emby.header {
-		req.Header.Add(name, value)
-	}
-	resp, err := emby.httpClient.Do(req)
-	defer emby.timeoutHandler()
-	defer resp.Body.Close()
-	if resp.StatusCode == 404 {
+	_, status, err := emby.delete(ctx, url)
+	if status == 404 {
 		err = ErrUserNotFound{id: userID}
-	} else if customErr := emby.genericErr(resp.StatusCode, ""); customErr != nil {
+	} else if customErr := emby.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
 	return err
@@ -30,13 +24,13 @@ func embyDeleteUser(emby *MediaBrowser, userID string) error {
 // Immediately disable it
 // Set password
 // Re-enable it
-func embyNewUser(emby *MediaBrowser, username, password string) (User, error) {
+func embyNewUser(ctx context.Context, emby *MediaBrowser, username, password string) (User, error) {
 	url := fmt.Sprintf("%s/Users/New", emby.Server)
 	data := map[string]interface{}{
 		"Name": username,
 	}
-	response, status, err := emby.post(url, data, true)
-	if customErr := emby.genericErr(status, ""); customErr != nil {
+	response, status, err := emby.post(ctx, url, data, true)
+	if customErr := emby.genericErr(status, ""); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
@@ -53,13 +47,14 @@ func embyNewUser(emby *MediaBrowser, username, password string) (User, error) {
 		"NewPw":     password,
 	}
 	var resp string
-	resp, status, err = emby.post(url, data, true)
-	if customErr := emby.genericErr(status, resp); customErr != nil {
+	resp, status, err = emby.post(ctx, url, data, true)
+	if customErr := emby.genericErr(status, resp); err == nil && customErr != nil {
 		err = customErr
 	}
 	// Step 3: If setting password errored, try to delete the account
 	if err != nil {
-		err = emby.DeleteUser(id)
+		emby.DeleteUserContext(ctx, id)
+		return User{}, err
 	}
-	return recv, err
+	return recv, nil
 }
@@ -1,37 +1,30 @@
 package mediabrowser
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 )
 
-func jfDeleteUser(jf *MediaBrowser, userID string) error {
+func jfDeleteUser(ctx context.Context, jf *MediaBrowser, userID string) error {
 	url := fmt.Sprintf("%s/Users/%s", jf.Server, userID)
-	req, _ := http.NewRequest("DELETE", url, nil)
-	for name, value := range jf.header {
-		req.Header.Add(name, value)
-	}
-	resp, err := jf.httpClient.Do(req)
-	defer jf.timeoutHandler()
-	defer resp.Body.Close()
-	data := ""
-	if jf.Verbose {
-		data = bodyToString(resp)
+	data, status, err := jf.delete(ctx, url)
+	if !jf.Verbose {
+		data = ""
 	}
 	// Should be 404 but sometimes isn't
-	if resp.StatusCode == 404 || resp.StatusCode == 500 {
+	if status == 404 || status == 500 {
 		err = ErrUserNotFound{id: userID}
 		if jf.Verbose {
 			json.Unmarshal([]byte(data), &err)
 		}
-	} else if customErr := jf.genericErr(resp.StatusCode, data); customErr != nil {
+	} else if customErr := jf.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
 	return err
 }
 
-func jfNewUser(jf *MediaBrowser, username, password string) (User, error) {
+func jfNewUser(ctx context.Context, jf *MediaBrowser, username, password string) (User, error) {
 	url := fmt.Sprintf("%s/Users/New", jf.Server)
 	stringData := map[string]string{
 		"Name":     username,
@@ -41,8 +34,8 @@ func jfNewUser(jf *MediaBrowser, username, password string) (User, error) {
 	for key, value := range stringData {
 		data[key] = value
 	}
-	resp, status, err := jf.post(url, data, true)
-	if customErr := jf.genericErr(status, resp); customErr != nil {
+	resp, status, err := jf.post(ctx, url, data, true)
+	if customErr := jf.genericErr(status, resp); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
@@ -53,12 +46,12 @@ func jfNewUser(jf *MediaBrowser, username, password string) (User, error) {
 	return recv, nil
 }
 
-func jfResetPassword(jf *MediaBrowser, pin string) (PasswordResetResponse, error) {
+func jfResetPassword(ctx context.Context, jf *MediaBrowser, pin string) (PasswordResetResponse, error) {
 	url := fmt.Sprintf("%s/Users/ForgotPassword/Pin", jf.Server)
-	resp, status, err := jf.post(url, map[string]string{
+	resp, status, err := jf.post(ctx, url, map[string]string{
 		"Pin": pin,
 	}, true)
-	if customErr := jf.genericErr(status, resp); customErr != nil {
+	if customErr := jf.genericErr(status, resp); err == nil && customErr != nil {
 		err = customErr
 	}
 	recv := PasswordResetResponse{}
@@ -3,6 +3,8 @@ package mediabrowser
 // Shared functions that work the same on Jellyfin & Emby.
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -11,124 +13,346 @@ import (
 
 // GetUsers returns all (visible) users on the instance. If public, no authentication is needed but hidden users will not be visible.
 func (mb *MediaBrowser) GetUsers(public bool) ([]User, error) {
-	if !public && !mb.Authenticated {
-		_, err := mb.Authenticate(mb.Username, mb.password)
-		if err != nil {
+	return mb.GetUsersContext(context.Background(), public)
+}
+
+// GetUsersContext is GetUsers with a context controlling the request.
+func (mb *MediaBrowser) GetUsersContext(ctx context.Context, public bool) ([]User, error) {
+	if !public {
+		if err := mb.ensureAuthenticated(ctx); err != nil {
 			return []User{}, err
 		}
 	}
-	if err := mb.syncUserCache(public); err != nil {
+	if err := mb.syncUserCache(ctx, public); err != nil {
 		return nil, err
 	}
-	return mb.userCache, nil
+	mb.cacheLock.RLock()
+	defer mb.cacheLock.RUnlock()
+	// Copied, as the cache may be patched in place (see cacheUser).
+	users := make([]User, len(mb.userCache))
+	copy(users, mb.userCache)
+	return users, nil
 }
 
-func (mb *MediaBrowser) syncUserCache(public bool) error {
-	if mb.CacheExpiry.After(time.Now()) {
-		return nil
-	}
+// cacheSync represents an in-progress refresh of a cache, shared by everyone waiting on it.
+type cacheSync struct {
+	done    chan struct{} // Closed once the refresh is finished, after which err and dropped are set.
+	err     error
+	waiters int                // Number of callers waiting, guarded by cacheLock.
+	cancel  context.CancelFunc // Cancels the refresh, called once all waiters have given up.
+	// Set (under cacheLock) if the cache is changed while the refresh runs, so its result may be older than the change.
+	dirty bool
+	// Set if the result was thrown away because a newer refresh replaced this one, which waiters should wait on instead.
+	dropped bool
+}
 
-	syncStatus := make(chan error)
+// Number of times a refresh is attempted if the cache keeps changing while it runs, see cacheSync.dirty.
+const maxRefreshAttempts = 3
 
-	go func(syncStatus chan error, mb *MediaBrowser) {
-		mb.syncLock.Lock()
-		alreadySyncing := mb.syncing
-		// We're either already syncing or will be
-		mb.syncing = true
-		mb.syncLock.Unlock()
-		if !alreadySyncing {
-			var result []User
-			var data string
-			var status int
-			var err error
+// syncUserCache reloads the user cache if it has expired.
+// Concurrent callers share a single refresh, which is only cancelled once every caller's context is.
+func (mb *MediaBrowser) syncUserCache(ctx context.Context, public bool) error {
+	for {
+		mb.cacheLock.Lock()
+		if mb.CacheExpiry.After(time.Now()) || (mb.liveUserCache && mb.liveUserCacheLoaded) {
+			mb.cacheLock.Unlock()
+			return nil
+		}
+		call := mb.userSync
+		if call == nil {
+			syncCtx, cancel := context.WithCancel(context.Background())
+			call = &cacheSync{done: make(chan struct{}), cancel: cancel}
+			mb.userSync = call
+			go mb.refreshUserCache(syncCtx, public, call)
+		}
+		call.waiters++
+		mb.cacheLock.Unlock()
 
-			if public {
-				url := fmt.Sprintf("%s/users/public", mb.Server)
-				data, status, err = mb.get(url, nil)
-			} else {
-				url := fmt.Sprintf("%s/users", mb.Server)
-				data, status, err = mb.get(url, mb.loginParams)
-			}
-			if customErr := mb.genericErr(status, data); customErr != nil {
-				err = customErr
-			}
-			if err != nil {
-				mb.syncLock.Lock()
-				mb.syncing = false
-				mb.syncLock.Unlock()
-				syncStatus <- err
-				return
-			}
-			err = json.Unmarshal([]byte(data), &result)
-			if err != nil {
-				mb.syncLock.Lock()
-				mb.syncing = false
-				mb.syncLock.Unlock()
-				syncStatus <- err
-				return
-			}
-			mb.userCache = result
-			mb.usersByID = map[string]int{}
-			mb.usersByName = map[string]int{}
-			for i := range mb.userCache {
-				mb.usersByID[mb.userCache[i].ID] = i
-				// While usernames have case, Jellyfin (at least) counts identical usernames with different cases as identical.
-				mb.usersByName[strings.ToLower(mb.userCache[i].Name)] = i
+		// Wait for completion
+		select {
+		case <-call.done:
+			if call.dropped {
+				continue
 			}
-			mb.CacheExpiry = time.Now().Add(time.Minute * time.Duration(mb.cacheLength))
-			// Quirk
-			if len(result) != 0 && result[0].ID[8] == '-' {
-				mb.Hyphens = true
+			return call.err
+		case <-ctx.Done():
+			mb.cacheLock.Lock()
+			call.waiters--
+			if call.waiters == 0 {
+				call.cancel()
+				// Let the next caller start a fresh refresh, rather than wait on this cancelled one.
+				if mb.userSync == call {
+					mb.userSync = nil
+				}
 			}
+			mb.cacheLock.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// refreshUserCache loads the user list, trying again if the cache was changed in the meantime (see cacheSync.dirty).
+func (mb *MediaBrowser) refreshUserCache(ctx context.Context, public bool, call *cacheSync) {
+	defer call.cancel()
+	for attempt := 1; ; attempt++ {
+		result, expiry, err := mb.fetchUsers(ctx, public)
+		mb.cacheLock.Lock()
+		retry := err == nil && call.dirty && attempt < maxRefreshAttempts
+		if retry {
+			call.dirty = false
+		}
+		mb.cacheLock.Unlock()
+		if !retry {
+			mb.storeUserCache(result, expiry, call, err)
+			return
+		}
+	}
+}
+
+// fetchUsers loads the user list from mb.cache or the server, returning when it expires.
+func (mb *MediaBrowser) fetchUsers(ctx context.Context, public bool) (result []User, expiry time.Time, err error) {
+	var data string
+	var status int
+
+	key := mb.userCacheKey(public)
+	mb.cacheLock.RLock()
+	loaded := mb.userCacheEntry
+	mb.cacheLock.RUnlock()
+	if cached, expiry, ok := mb.cachedResponse(key, loaded); ok && json.Unmarshal(cached, &result) == nil {
+		return result, expiry, nil
+	}
+	result = nil
+
+	raw := mb.responseBuffer()
+	if public {
+		url := fmt.Sprintf("%s/users/public", mb.Server)
+		data, status, err = mb.getJSON(ctx, url, nil, &result, raw)
+	} else {
+		url := fmt.Sprintf("%s/users", mb.Server)
+		_, loginParams := mb.credentials()
+		data, status, err = mb.getJSON(ctx, url, loginParams, &result, raw)
+	}
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
+		err = customErr
+	}
+	if err == nil {
+		expiry = mb.storeResponse(key, raw)
+	}
+	return
+}
 
-			mb.syncLock.Lock()
-			mb.syncing = false
-			mb.syncLock.Unlock()
+// storeUserCache finishes a refresh, storing the result if err is nil and waking everyone waiting on it.
+// The result of an abandoned or replaced refresh is dropped, as a newer one may have replaced it.
+// If the cache changed while the refresh ran, the result is stored but left expired, so the next caller loads it again.
+func (mb *MediaBrowser) storeUserCache(result []User, expiry time.Time, call *cacheSync, err error) {
+	mb.cacheLock.Lock()
+	current := mb.userSync == call
+	if current && err == nil {
+		mb.userCache = result
+		mb.indexUserCache()
+		mb.userCacheEntry = expiry
+		if call.dirty {
+			mb.CacheExpiry = time.Now()
 		} else {
-			for mb.syncing {
-				continue
-			}
+			mb.CacheExpiry = expiry
+			mb.liveUserCacheLoaded = mb.liveUserCache
 		}
-		syncStatus <- nil
-	}(syncStatus, mb)
+		// Quirk
+		if len(result) != 0 && len(result[0].ID) > 8 && result[0].ID[8] == '-' {
+			mb.Hyphens = true
+		}
+	}
+	call.err = err
+	call.dropped = !current
+	if current {
+		mb.userSync = nil
+	}
+	mb.cacheLock.Unlock()
+	close(call.done)
+}
 
-	// Wait for completion
-	err := <-syncStatus
-	return err
+// indexUserCache rebuilds the ID and name maps for userCache. cacheLock must be held.
+func (mb *MediaBrowser) indexUserCache() {
+	mb.usersByID = map[string]int{}
+	mb.usersByName = map[string]int{}
+	for i := range mb.userCache {
+		mb.usersByID[mb.userCache[i].ID] = i
+		// While usernames have case, Jellyfin (at least) counts identical usernames with different cases as identical.
+		mb.usersByName[strings.ToLower(mb.userCache[i].Name)] = i
+	}
+}
+
+// cachedUserByID returns the user with the given ID from the cache, without reloading it.
+func (mb *MediaBrowser) cachedUserByID(userID string) (User, bool) {
+	mb.cacheLock.RLock()
+	defer mb.cacheLock.RUnlock()
+	if i, ok := mb.usersByID[userID]; ok {
+		return mb.userCache[i], true
+	}
+	return User{}, false
+}
+
+// cachedUserByName returns the user with the given (lower-case) name from the cache, without reloading it.
+func (mb *MediaBrowser) cachedUserByName(username string) (User, bool) {
+	mb.cacheLock.RLock()
+	defer mb.cacheLock.RUnlock()
+	if i, ok := mb.usersByName[username]; ok {
+		return mb.userCache[i], true
+	}
+	return User{}, false
+}
+
+// cacheUser adds or replaces the given user in the user cache.
+// Nothing is done if the cache hasn't been loaded yet, as it'll include the user when it is.
+func (mb *MediaBrowser) cacheUser(user User) {
+	if user.ID == "" {
+		return
+	}
+	defer mb.invalidateSharedUserCache()
+	mb.cacheLock.Lock()
+	defer mb.cacheLock.Unlock()
+	mb.markUserSyncDirty()
+	if mb.userCache == nil {
+		return
+	}
+	if i, ok := mb.usersByID[user.ID]; ok {
+		old := strings.ToLower(mb.userCache[i].Name)
+		mb.userCache[i] = user
+		if name := strings.ToLower(user.Name); name != old {
+			delete(mb.usersByName, old)
+			mb.usersByName[name] = i
+		}
+		return
+	}
+	mb.userCache = append(mb.userCache, user)
+	i := len(mb.userCache) - 1
+	mb.usersByID[user.ID] = i
+	mb.usersByName[strings.ToLower(user.Name)] = i
+}
+
+// patchCachedUser applies the given change to the cached copy of a user, if there is one.
+// Used to keep the cache consistent after we change something ourselves.
+func (mb *MediaBrowser) patchCachedUser(userID string, patch func(*User)) {
+	mb.cacheLock.Lock()
+	mb.markUserSyncDirty()
+	if i, ok := mb.usersByID[userID]; ok {
+		patch(&mb.userCache[i])
+	}
+	mb.cacheLock.Unlock()
+	mb.invalidateSharedUserCache()
+}
+
+// uncacheUser removes the user with the given ID from the user cache.
+func (mb *MediaBrowser) uncacheUser(userID string) {
+	mb.cacheLock.Lock()
+	mb.markUserSyncDirty()
+	i, ok := mb.usersByID[userID]
+	if ok {
+		mb.userCache = append(mb.userCache[:i:i], mb.userCache[i+1:]...)
+		mb.indexUserCache()
+	}
+	mb.cacheLock.Unlock()
+	mb.invalidateSharedUserCache()
+}
+
+// markUserSyncDirty notes that the user cache has changed during any in-progress refresh, whose result may then be out of date.
+// cacheLock must be held.
+func (mb *MediaBrowser) markUserSyncDirty() {
+	if mb.userSync != nil {
+		mb.userSync.dirty = true
+	}
+}
+
+// invalidateSharedUserCache removes the user list from mb.cache after the local copy has been changed,
+// so anyone sharing it doesn't pick up the outdated version.
+func (mb *MediaBrowser) invalidateSharedUserCache() {
+	cache := mb.sharedCache()
+	if cache == nil {
+		return
+	}
+	cache.Invalidate(mb.userCacheKey(false))
+	cache.Invalidate(mb.userCacheKey(true))
+}
+
+// EnableLiveUserCache keeps the user cache up to date with UserUpdated, UserPolicyUpdated, UserConfigurationUpdated and UserDeleted events
+// from the given socket (see NewEventSocket), so it doesn't need to expire while the socket is connected.
+// The cache is reloaded each time the socket (re)connects, as events may have been missed, and falls back to expiring after CacheExpiry while disconnected.
+// Call before socket.Run.
+func (mb *MediaBrowser) EnableLiveUserCache(socket *EventSocket) {
+	socket.OnConnect(func() {
+		mb.cacheLock.Lock()
+		defer mb.cacheLock.Unlock()
+		mb.CacheExpiry = time.Now()
+		mb.userCache = nil
+		mb.indexUserCache()
+		// A refresh started before connecting could miss events since, so its result is dropped.
+		mb.userSync = nil
+		mb.liveUserCache = true
+		mb.liveUserCacheLoaded = false
+	})
+	socket.OnDisconnect(func(error) {
+		mb.cacheLock.Lock()
+		defer mb.cacheLock.Unlock()
+		mb.liveUserCache = false
+	})
+	update := func(e Event) {
+		if e.User != nil {
+			mb.cacheUser(*e.User)
+		}
+	}
+	socket.On(EventUserUpdated, update)
+	socket.On(EventUserPolicyUpdated, update)
+	socket.On(EventUserConfigurationUpdated, update)
+	socket.On(EventUserDeleted, func(e Event) {
+		mb.uncacheUser(e.UserID)
+	})
 }
 
 // UserByID returns the user corresponding to the provided ID.
 func (mb *MediaBrowser) UserByID(userID string, public bool) (User, error) {
+	return mb.UserByIDContext(context.Background(), userID, public)
+}
+
+// UserByIDContext is UserByID with a context controlling the request(s).
+func (mb *MediaBrowser) UserByIDContext(ctx context.Context, userID string, public bool) (User, error) {
 	if userID == "" {
 		return User{}, ErrUserNotFound{}
 	}
-	if u, err := mb.UserByIDFromCache(userID); err == nil {
+	if u, err := mb.UserByIDFromCacheContext(ctx, userID); err == nil {
 		return u, err
 	}
 	// If the user isn't found in the cache then we update it
-	if !mb.Authenticated {
-		_, err := mb.Authenticate(mb.Username, mb.password)
-		if err != nil {
-			return User{}, err
-		}
+	if err := mb.ensureAuthenticated(ctx); err != nil {
+		return User{}, err
 	}
 
 	if public {
-		_, err := mb.GetUsers(public)
+		_, err := mb.GetUsersContext(ctx, public)
 		if err != nil {
 			return User{}, err
 		}
-		if i, ok := mb.usersByID[userID]; ok {
-			return mb.userCache[i], nil
+		if u, ok := mb.cachedUserByID(userID); ok {
+			return u, nil
 		}
 		return User{}, ErrUserNotFound{id: userID}
 	}
+	return mb.userByIDFresh(ctx, userID, false)
+}
+
+// userByIDFresh fetches the user directly from the server, bypassing the cache.
+// If strict is true, a user that doesn't fully decode is an error rather than returned partially.
+func (mb *MediaBrowser) userByIDFresh(ctx context.Context, userID string, strict bool) (User, error) {
 	var result User
 	var data string
 	var status int
 	var err error
 	url := fmt.Sprintf("%s/users/%s", mb.Server, userID)
-	data, status, err = mb.get(url, mb.loginParams)
+	_, loginParams := mb.credentials()
+	data, status, err = mb.getJSON(ctx, url, loginParams, &result, nil)
+	if status == 200 && !strict {
+		// As before streaming, a user that doesn't fully decode is still returned.
+		err = nil
+	}
 	if (status == 404 && (mb.serverType == EmbyServer || data == "\"User not found\"")) || status == 400 {
 		// 400 is really an "invalid ID", but we'll keep it as this for now.
 		newErr := ErrUserNotFound{id: userID}
@@ -138,68 +362,79 @@ func (mb *MediaBrowser) UserByID(userID string, public bool) (User, error) {
 			newErr.Data = data
 		}
 		err = newErr
-	} else if customErr := mb.genericErr(status, data); customErr != nil {
+	} else if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
 		return User{}, err
 	}
-	json.Unmarshal([]byte(data), &result)
 	return result, nil
 }
 
 // UserByIDFromCache searches only the local cache (reloading it if dated) for the user, rather than falling back to Jellyfin/Emby.
 func (mb *MediaBrowser) UserByIDFromCache(userID string) (User, error) {
+	return mb.UserByIDFromCacheContext(context.Background(), userID)
+}
+
+// UserByIDFromCacheContext is UserByIDFromCache with a context controlling any cache reload.
+func (mb *MediaBrowser) UserByIDFromCacheContext(ctx context.Context, userID string) (User, error) {
 	if userID == "" {
 		return User{}, ErrUserNotFound{}
 	}
-	if err := mb.syncUserCache(false); err != nil {
+	if err := mb.syncUserCache(ctx, false); err != nil {
 		return User{}, err
 	}
-	if i, ok := mb.usersByID[userID]; ok {
-		return mb.userCache[i], nil
+	if u, ok := mb.cachedUserByID(userID); ok {
+		return u, nil
 	}
 	return User{}, ErrUserNotFound{id: userID}
 }
 
 // UserByName returns the user corresponding to the provided username.
 func (mb *MediaBrowser) UserByName(username string, public bool) (User, error) {
+	return mb.UserByNameContext(context.Background(), username, public)
+}
+
+// UserByNameContext is UserByName with a context controlling any cache reload.
+func (mb *MediaBrowser) UserByNameContext(ctx context.Context, username string, public bool) (User, error) {
 	if username == "" {
 		return User{}, ErrUserNotFound{}
 	}
-	if err := mb.syncUserCache(false); err != nil {
+	if err := mb.syncUserCache(ctx, false); err != nil {
 		return User{}, err
 	}
 	username = strings.ToLower(username)
-	find := func() int {
-		if i, ok := mb.usersByName[username]; ok {
-			return i
-		}
-		return -1
+	if u, ok := mb.cachedUserByName(username); ok {
+		return u, nil
 	}
-
-	idx := find()
-	if idx == -1 {
-		// Force-reload cache if not found
-		mb.CacheExpiry = time.Now()
-		idx := find()
-		if idx == -1 {
-			return User{}, ErrUserNotFound{user: username}
-		}
+	// Force-reload cache if not found
+	mb.cacheLock.Lock()
+	mb.CacheExpiry = time.Now()
+	mb.cacheLock.Unlock()
+	if err := mb.syncUserCache(ctx, false); err != nil {
+		return User{}, err
+	}
+	if u, ok := mb.cachedUserByName(username); ok {
+		return u, nil
 	}
-	return mb.userCache[idx], nil
+	return User{}, ErrUserNotFound{user: username}
 }
 
 // UserByNameFromCache searches only the local cache (reloading it if dated) for the user, rather than falling back to Jellyfin/Emby.
 func (mb *MediaBrowser) UserByNameFromCache(username string) (User, error) {
+	return mb.UserByNameFromCacheContext(context.Background(), username)
+}
+
+// UserByNameFromCacheContext is UserByNameFromCache with a context controlling any cache reload.
+func (mb *MediaBrowser) UserByNameFromCacheContext(ctx context.Context, username string) (User, error) {
 	if username == "" {
 		return User{}, ErrUserNotFound{}
 	}
-	if err := mb.syncUserCache(false); err != nil {
+	if err := mb.syncUserCache(ctx, false); err != nil {
 		return User{}, err
 	}
-	if i, ok := mb.usersByName[username]; ok {
-		return mb.userCache[i], nil
+	if u, ok := mb.cachedUserByName(strings.ToLower(username)); ok {
+		return u, nil
 	}
 	return User{}, ErrUserNotFound{user: username}
 }
@@ -207,55 +442,145 @@ func (mb *MediaBrowser) UserByNameFromCache(username string) (User, error) {
 // SetPolicy sets the access policy for the user corresponding to the provided ID.
 // No GetPolicy is provided because a User object includes Policy already.
 func (mb *MediaBrowser) SetPolicy(userID string, policy Policy) error {
+	return mb.SetPolicyContext(context.Background(), userID, policy)
+}
+
+// SetPolicyContext is SetPolicy with a context controlling the request.
+func (mb *MediaBrowser) SetPolicyContext(ctx context.Context, userID string, policy Policy) error {
 	url := fmt.Sprintf("%s/Users/%s/Policy", mb.Server, userID)
 	DeNullPolicy(&policy)
-	data, status, err := mb.post(url, policy, true)
+	data, status, err := mb.post(ctx, url, policy, true)
 	if status == 400 {
 		err = ErrNoPolicySupplied{}
 		if mb.Verbose {
 			json.Unmarshal([]byte(data), &err)
 		}
-	} else if customErr := mb.genericErr(status, data); customErr != nil {
+	} else if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.patchCachedUser(userID, func(u *User) { u.Policy = policy })
+	}
 	return err
 }
 
+// maxPatchAttempts is how many times PatchPolicy & PatchConfiguration try before giving up with ErrPatchConflict.
+const maxPatchAttempts = 5
+
+// PatchPolicy applies patch to the user's current policy (fetched fresh from the server) and saves the result.
+// If the policy is changed by someone else in the meantime, it starts again with the new version, up to a few times.
+// The check happens just before saving, so a change landing in between the two requests can still be overwritten.
+func (mb *MediaBrowser) PatchPolicy(userID string, patch func(*Policy)) error {
+	return mb.PatchPolicyContext(context.Background(), userID, patch)
+}
+
+// PatchPolicyContext is PatchPolicy with a context controlling the requests.
+func (mb *MediaBrowser) PatchPolicyContext(ctx context.Context, userID string, patch func(*Policy)) error {
+	return mb.patchUser(ctx, userID,
+		func(u *User) interface{} { return u.Policy },
+		func(u *User) { patch(&u.Policy) },
+		func(u User) error { return mb.SetPolicyContext(ctx, userID, u.Policy) },
+	)
+}
+
+// PatchConfiguration is PatchPolicy for the user's Configuration.
+func (mb *MediaBrowser) PatchConfiguration(userID string, patch func(*Configuration)) error {
+	return mb.PatchConfigurationContext(context.Background(), userID, patch)
+}
+
+// PatchConfigurationContext is PatchConfiguration with a context controlling the requests.
+func (mb *MediaBrowser) PatchConfigurationContext(ctx context.Context, userID string, patch func(*Configuration)) error {
+	return mb.patchUser(ctx, userID,
+		func(u *User) interface{} { return u.Configuration },
+		func(u *User) { patch(&u.Configuration) },
+		func(u User) error { return mb.SetConfigurationContext(ctx, userID, u.Configuration) },
+	)
+}
+
+// patchUser does a read-modify-write of the part of the user returned by field, comparing versions by their JSON encoding.
+// A user that doesn't fully decode fails the patch, as saving it would drop whatever didn't.
+func (mb *MediaBrowser) patchUser(ctx context.Context, userID string, field func(*User) interface{}, patch func(*User), save func(User) error) error {
+	if err := mb.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		user, err := mb.userByIDFresh(ctx, userID, true)
+		if err != nil {
+			return err
+		}
+		before, err := json.Marshal(field(&user))
+		if err != nil {
+			return err
+		}
+		patch(&user)
+		after, err := json.Marshal(field(&user))
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(before, after) {
+			return nil
+		}
+		current, err := mb.userByIDFresh(ctx, userID, true)
+		if err != nil {
+			return err
+		}
+		if now, _ := json.Marshal(field(&current)); !bytes.Equal(before, now) {
+			continue
+		}
+		return save(user)
+	}
+	return ErrPatchConflict{userID: userID, attempts: maxPatchAttempts}
+}
+
 // SetConfiguration sets the configuration (part of homescreen layout) for the user corresponding to the provided ID.
 // No GetConfiguration is provided because a User object includes Configuration already.
 func (mb *MediaBrowser) SetConfiguration(userID string, configuration Configuration) error {
+	return mb.SetConfigurationContext(context.Background(), userID, configuration)
+}
+
+// SetConfigurationContext is SetConfiguration with a context controlling the request.
+func (mb *MediaBrowser) SetConfigurationContext(ctx context.Context, userID string, configuration Configuration) error {
 	url := fmt.Sprintf("%s/Users/%s/Configuration", mb.Server, userID)
 	DeNullConfiguration(&configuration)
-	data, status, err := mb.post(url, configuration, true)
-	if customErr := mb.genericErr(status, data); customErr != nil {
+	data, status, err := mb.post(ctx, url, configuration, true)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.patchCachedUser(userID, func(u *User) { u.Configuration = configuration })
+	}
 	return err
 }
 
 // GetDisplayPreferences gets the displayPreferences (part of homescreen layout) for the user corresponding to the provided ID.
 func (mb *MediaBrowser) GetDisplayPreferences(userID string) (map[string]interface{}, error) {
+	return mb.GetDisplayPreferencesContext(context.Background(), userID)
+}
+
+// GetDisplayPreferencesContext is GetDisplayPreferences with a context controlling the request.
+func (mb *MediaBrowser) GetDisplayPreferencesContext(ctx context.Context, userID string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/DisplayPreferences/usersettings?userId=%s&client=emby", mb.Server, userID)
-	data, status, err := mb.get(url, nil)
-	if customErr := mb.genericErr(status, data); customErr != nil {
+	var displayprefs map[string]interface{}
+	data, status, err := mb.getJSON(ctx, url, nil, &displayprefs, nil)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
 		return nil, err
 	}
-	var displayprefs map[string]interface{}
-	err = json.Unmarshal([]byte(data), &displayprefs)
-	if err != nil {
-		return nil, err
-	}
 	return displayprefs, nil
 }
 
 // SetDisplayPreferences sets the displayPreferences (part of homescreen layout) for the user corresponding to the provided ID.
 func (mb *MediaBrowser) SetDisplayPreferences(userID string, displayprefs map[string]interface{}) error {
+	return mb.SetDisplayPreferencesContext(context.Background(), userID, displayprefs)
+}
+
+// SetDisplayPreferencesContext is SetDisplayPreferences with a context controlling the request.
+func (mb *MediaBrowser) SetDisplayPreferencesContext(ctx context.Context, userID string, displayprefs map[string]interface{}) error {
 	url := fmt.Sprintf("%s/DisplayPreferences/usersettings?userId=%s&client=emby", mb.Server, userID)
-	data, status, err := mb.post(url, displayprefs, true)
-	if customErr := mb.genericErr(status, data); customErr != nil {
+	data, status, err := mb.post(ctx, url, displayprefs, true)
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
 	if err != nil {
@@ -266,27 +591,49 @@ func (mb *MediaBrowser) SetDisplayPreferences(userID string, displayprefs map[st
 
 // SetPassword sets the password for a user given a userID, the old password, and the new one. Requires admin authentication or authentication as the target user.
 func (mb *MediaBrowser) SetPassword(userID, currentPw, newPw string) error {
+	return mb.SetPasswordContext(context.Background(), userID, currentPw, newPw)
+}
+
+// SetPasswordContext is SetPassword with a context controlling the request.
+func (mb *MediaBrowser) SetPasswordContext(ctx context.Context, userID, currentPw, newPw string) error {
 	url := fmt.Sprintf("%s/Users/%s/Password", mb.Server, userID)
-	data, status, err := mb.post(url, setPasswordRequest{
+	data, status, err := mb.post(ctx, url, setPasswordRequest{
 		Current:       currentPw,
 		CurrentPw:     currentPw,
 		New:           newPw,
 		ResetPassword: false,
 	}, true)
-	if customErr := mb.genericErr(status, data); customErr != nil {
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.patchCachedUser(userID, func(u *User) {
+			u.HasPassword = newPw != ""
+			u.HasConfiguredPassword = u.HasPassword
+		})
+	}
 	return err
 }
 
 // ResetPasswordAdmin resets the given user ID's password, allowing one to then change it without knowing the previous password.
 func (mb *MediaBrowser) ResetPasswordAdmin(userID string) error {
+	return mb.ResetPasswordAdminContext(context.Background(), userID)
+}
+
+// ResetPasswordAdminContext is ResetPasswordAdmin with a context controlling the request.
+func (mb *MediaBrowser) ResetPasswordAdminContext(ctx context.Context, userID string) error {
 	url := fmt.Sprintf("%s/Users/%s/Password", mb.Server, userID)
-	data, status, err := mb.post(url, map[string]bool{
+	data, status, err := mb.post(ctx, url, map[string]bool{
 		"ResetPassword": true,
 	}, true)
-	if customErr := mb.genericErr(status, data); customErr != nil {
+	if customErr := mb.genericErr(status, data); err == nil && customErr != nil {
 		err = customErr
 	}
+	if err == nil {
+		mb.patchCachedUser(userID, func(u *User) {
+			u.HasPassword = false
+			u.HasConfiguredPassword = false
+		})
+	}
 	return err
 }
@@ -0,0 +1,285 @@
+package mediabrowser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testUsers = `[{"Id":"aaaaaaaaaaaa","Name":"alice"},{"Id":"bbbbbbbbbbbb","Name":"Bob"}]`
+
+// Run with -race to check for data races.
+func TestConcurrentUse(t *testing.T) {
+	var userRequests, authRequests int32
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			atomic.AddInt32(&userRequests, 1)
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte(testUsers))
+		case "/Users/authenticatebyname":
+			n := atomic.AddInt32(&authRequests, 1)
+			fmt.Fprintf(w, `{"AccessToken":"token%d","User":{"Id":"aaaaaaaaaaaa","Name":"alice"}}`, n)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	if _, err := mb.Authenticate("alice", "password"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 40; i++ {
+		wg.Add(5)
+		go func() {
+			defer wg.Done()
+			if _, err := mb.UserByID("aaaaaaaaaaaa", false); err != nil {
+				errs <- fmt.Errorf("UserByID: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := mb.UserByName("bob", false); err != nil {
+				errs <- fmt.Errorf("UserByName: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if users, err := mb.GetUsers(false); err != nil || len(users) < 2 {
+				errs <- fmt.Errorf("GetUsers: %d users, %v", len(users), err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := mb.SetPolicy("bbbbbbbbbbbb", Policy{}); err != nil {
+				errs <- fmt.Errorf("SetPolicy: %v", err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if i%10 == 0 {
+				if _, err := mb.Authenticate("alice", "password"); err != nil {
+					errs <- fmt.Errorf("Authenticate: %v", err)
+				}
+			}
+			mb.cacheUser(User{ID: fmt.Sprintf("cccccccccc%02d", i), Name: fmt.Sprintf("carol%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	// The shared refresh is repeated as the cache is changed while it runs, but callers shouldn't start their own.
+	if n := atomic.LoadInt32(&userRequests); n < 1 || n > maxRefreshAttempts {
+		t.Errorf("user list was requested %d times, expected 1-%d", n, maxRefreshAttempts)
+	}
+}
+
+func TestSharedRefreshCancellation(t *testing.T) {
+	release := make(chan struct{})
+	var lock sync.Mutex
+	hang := false
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/public" {
+			return
+		}
+		lock.Lock()
+		hanging := hang
+		lock.Unlock()
+		if hanging {
+			<-r.Context().Done()
+			return
+		}
+		<-release
+		w.Write([]byte(testUsers))
+	}))
+
+	// The first caller gives up, but the refresh should carry on for the second.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := mb.GetUsersContext(ctx, true)
+		first <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	second := make(chan error)
+	go func() {
+		_, err := mb.GetUsersContext(context.Background(), true)
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("first caller got %v, expected context.Canceled", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("second caller got %v, expected success", err)
+	}
+
+	// Once every caller gives up, the refresh is abandoned, and the next caller should start a fresh one rather than join it.
+	lock.Lock()
+	hang = true
+	lock.Unlock()
+	mb.cacheLock.Lock()
+	mb.CacheExpiry = time.Time{}
+	mb.cacheLock.Unlock()
+	ctx, cancel = context.WithCancel(context.Background())
+	third := make(chan error)
+	go func() {
+		_, err := mb.GetUsersContext(ctx, true)
+		third <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	lock.Lock()
+	hang = false
+	lock.Unlock()
+	cancel()
+	if err := <-third; err != context.Canceled {
+		t.Errorf("third caller got %v, expected context.Canceled", err)
+	}
+	if _, err := mb.GetUsers(true); err != nil {
+		t.Errorf("caller after abandoned refresh got %v, expected success", err)
+	}
+}
+
+func TestCacheFollowsLocalChanges(t *testing.T) {
+	var userRequests, libraryRequests int32
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			atomic.AddInt32(&userRequests, 1)
+			w.Write([]byte(testUsers))
+		case "/Users/New":
+			w.Write([]byte(`{"Id":"cccccccccccc","Name":"carol"}`))
+		case "/Library/VirtualFolders":
+			if r.Method == "GET" {
+				atomic.AddInt32(&libraryRequests, 1)
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+	if _, err := mb.GetUsers(false); err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+
+	if _, err := mb.NewUser("carol", "password"); err != nil {
+		t.Fatalf("NewUser failed: %v", err)
+	}
+	if user, err := mb.UserByNameFromCache("carol"); err != nil || user.ID != "cccccccccccc" {
+		t.Errorf("new user not cached: %+v, %v", user, err)
+	}
+	if err := mb.SetPolicy("aaaaaaaaaaaa", Policy{IsAdministrator: true}); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+	if user, _ := mb.UserByIDFromCache("aaaaaaaaaaaa"); !user.Policy.IsAdministrator {
+		t.Errorf("cached policy not updated")
+	}
+	if err := mb.DeleteUser("bbbbbbbbbbbb"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if users, _ := mb.GetUsers(false); len(users) != 2 {
+		t.Errorf("expected 2 users after add & delete, got %+v", users)
+	}
+	if n := atomic.LoadInt32(&userRequests); n != 1 {
+		t.Errorf("expected users to be fetched once, got %d", n)
+	}
+
+	mb.GetLibraries()
+	mb.GetLibraries()
+	if _, err := mb.AddLibrary("Movies", "movies", nil, false, LibraryOptions{}); err != nil {
+		t.Fatalf("AddLibrary failed: %v", err)
+	}
+	mb.GetLibraries()
+	if n := atomic.LoadInt32(&libraryRequests); n != 2 {
+		t.Errorf("expected libraries to be fetched twice, got %d", n)
+	}
+}
+
+func TestPatchPolicy(t *testing.T) {
+	var lock sync.Mutex
+	var gets int
+	alwaysChanging := false
+	policy := `{"IsHidden":false,"EnableRemoteAccess":false,"FutureSetting":true}`
+	var posted Policy
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.URL.Path == "/users/aaaaaaaaaaaa":
+			gets++
+			// Another admin hides the user between our first read and our write.
+			if gets == 2 || (alwaysChanging && gets%2 == 0) {
+				policy = fmt.Sprintf(`{"IsHidden":true,"EnableRemoteAccess":false,"FutureSetting":true,"Gen":%d}`, gets)
+			}
+			fmt.Fprintf(w, `{"Id":"aaaaaaaaaaaa","Name":"alice","Policy":%s}`, policy)
+		case r.URL.Path == "/Users/aaaaaaaaaaaa/Policy":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+
+	err := mb.PatchPolicy("aaaaaaaaaaaa", func(p *Policy) { p.EnableRemoteAccess = true })
+	if err != nil {
+		t.Fatalf("PatchPolicy failed: %v", err)
+	}
+	if !posted.EnableRemoteAccess || !posted.IsHidden || string(posted.Extra["FutureSetting"]) != "true" {
+		t.Errorf("concurrent change lost: %+v", posted)
+	}
+	if gets != 4 {
+		t.Errorf("expected a retry (4 reads), got %d reads", gets)
+	}
+
+	alwaysChanging = true
+	err = mb.PatchPolicy("aaaaaaaaaaaa", func(p *Policy) { p.IsDisabled = true })
+	if _, ok := err.(ErrPatchConflict); !ok {
+		t.Errorf("expected ErrPatchConflict, got %T: %v", err, err)
+	}
+}
+
+func TestPatchUndecodableUser(t *testing.T) {
+	posted := false
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/aaaaaaaaaaaa":
+			w.Write([]byte(`{"Id":"aaaaaaaaaaaa","Name":"alice","Policy":{"IsHidden":"yes","FutureSetting":true}}`))
+		case "/Users/aaaaaaaaaaaa/Policy":
+			posted = true
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	mb.Authenticated = true
+
+	// UserByID still returns what it could decode.
+	user, err := mb.UserByID("aaaaaaaaaaaa", false)
+	if err != nil || user.Name != "alice" {
+		t.Errorf("UserByID got %+v, %v, expected partial user", user, err)
+	}
+	if string(user.Policy.Extra["FutureSetting"]) != "true" {
+		t.Errorf("unknown fields lost on type error: %+v", user.Policy.Extra)
+	}
+	// Patches shouldn't save a policy missing the field that didn't decode.
+	if err := mb.PatchPolicy("aaaaaaaaaaaa", func(p *Policy) { p.EnableRemoteAccess = true }); err == nil {
+		t.Error("PatchPolicy succeeded, expected decode error")
+	}
+	if posted {
+		t.Error("partially decoded policy was posted")
+	}
+}
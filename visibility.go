@@ -0,0 +1,117 @@
+package mediabrowser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ItemAccessInfo is what's needed about an item to work out whether a user can see it. See Policy.ItemVisibility.
+type ItemAccessInfo struct {
+	// ID of the library (VirtualFolder.ItemId) the item is in, and on Emby its Guid, which policies may use instead. Hyphenation doesn't matter.
+	LibraryID, LibraryGuid string
+	// IDs of the library subfolders the item is in, checked against ExcludedSubFolders (Emby).
+	SubFolderIDs []string
+	// Tags of the item. The servers also apply tags set on an item's parents (e.g. the series of an episode), so include those too.
+	Tags []string
+	// Numeric value of the item's parental rating (see GetParentalRatings), or nil if it's unrated.
+	ParentalRating *int
+	// Kind of item, used when it's unrated to check BlockUnratedItems.
+	UnratedKind UnratedItem
+}
+
+// VisibilityRule names the part of a policy that decided whether an item is visible.
+type VisibilityRule string
+
+const (
+	RuleVisible            VisibilityRule = "Visible" // Nothing prevents access.
+	RuleUserDisabled       VisibilityRule = "IsDisabled"
+	RuleLibraryNotEnabled  VisibilityRule = "EnabledFolders"
+	RuleLibraryBlocked     VisibilityRule = "BlockedMediaFolders"
+	RuleSubFolderExcluded  VisibilityRule = "ExcludedSubFolders"
+	RuleBlockedTag         VisibilityRule = "BlockedTags"
+	RuleMissingAllowedTag  VisibilityRule = "AllowedTags"       // Jellyfin
+	RuleMissingIncludedTag VisibilityRule = "IncludeTags"       // Emby, when IsTagBlockingModeInclusive
+	RuleParentalRating     VisibilityRule = "MaxParentalRating" // Rated above the maximum.
+	RuleUnratedBlocked     VisibilityRule = "BlockUnratedItems"
+)
+
+// Visibility is the outcome of Policy.ItemVisibility.
+type Visibility struct {
+	Visible bool
+	Rule    VisibilityRule
+	Reason  string // Human-readable explanation, e.g. for a support ticket.
+}
+
+func stringsOf(values []interface{}) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// firstCommonTag returns the first of tags also in list, case-insensitively.
+func firstCommonTag(tags, list []string) (string, bool) {
+	for _, tag := range tags {
+		for _, other := range list {
+			if strings.EqualFold(tag, other) {
+				return tag, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ItemVisibility works out whether a user with this policy would see the given item, and which rule decided.
+// The first rule that hides the item is reported, checking in this order: IsDisabled, library access, tags, then parental rating.
+// As on the server, ratings (including BlockUnratedItems) are only checked when MaxParentalRating is set.
+func (p Policy) ItemVisibility(item ItemAccessInfo) Visibility {
+	hidden := func(rule VisibilityRule, format string, args ...interface{}) Visibility {
+		return Visibility{Visible: false, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+	}
+	if p.IsDisabled {
+		return hidden(RuleUserDisabled, "the user is disabled")
+	}
+
+	folders := p.libraryPolicy(libraryIDs(VirtualFolder{ItemId: item.LibraryID, Guid: item.LibraryGuid}), "")
+	switch folders.rule {
+	case RuleLibraryBlocked:
+		return hidden(RuleLibraryBlocked, "library %s is blocked", item.LibraryID)
+	case RuleLibraryNotEnabled:
+		return hidden(RuleLibraryNotEnabled, "library %s is not enabled for the user", item.LibraryID)
+	}
+	for _, id := range item.SubFolderIDs {
+		if folders.excluded[normalizeID(id)] {
+			return hidden(RuleSubFolderExcluded, "folder %s of library %s is excluded", id, item.LibraryID)
+		}
+	}
+
+	if tag, ok := firstCommonTag(item.Tags, stringsOf(p.BlockedTags)); ok {
+		return hidden(RuleBlockedTag, "tag %q is blocked", tag)
+	}
+	if allowed := stringsOf(p.AllowedTags); len(allowed) != 0 {
+		if _, ok := firstCommonTag(item.Tags, allowed); !ok {
+			return hidden(RuleMissingAllowedTag, "item has none of the allowed tags (%s)", strings.Join(allowed, ", "))
+		}
+	}
+	if p.IsTagBlockingModeInclusive && len(p.IncludeTags) != 0 {
+		if _, ok := firstCommonTag(item.Tags, p.IncludeTags); !ok {
+			return hidden(RuleMissingIncludedTag, "item has none of the included tags (%s)", strings.Join(p.IncludeTags, ", "))
+		}
+	}
+
+	if p.MaxParentalRating != nil {
+		if item.ParentalRating == nil {
+			for _, kind := range p.BlockUnratedItems {
+				if kind == item.UnratedKind {
+					return hidden(RuleUnratedBlocked, "unrated items of type %s are blocked", kind)
+				}
+			}
+		} else if *item.ParentalRating > *p.MaxParentalRating {
+			return hidden(RuleParentalRating, "rating %d is above the maximum of %d", *item.ParentalRating, *p.MaxParentalRating)
+		}
+	}
+	return Visibility{Visible: true, Rule: RuleVisible, Reason: "no rule prevents access"}
+}
@@ -0,0 +1,46 @@
+package mediabrowser
+
+import "testing"
+
+func TestItemVisibility(t *testing.T) {
+	intp := func(i int) *int { return &i }
+	const movies = "f137a2dd21bbc1b99aa5c0f6bf02a805"
+	base := Policy{
+		EnabledFolders:    []string{"f137a2dd-21bb-c1b9-9aa5-c0f6bf02a805"},
+		BlockedTags:       []interface{}{"Horror"},
+		MaxParentalRating: intp(13),
+		BlockUnratedItems: []UnratedItem{UnratedMovie},
+	}
+	cases := []struct {
+		name   string
+		policy func(p *Policy)
+		item   ItemAccessInfo
+		want   VisibilityRule
+	}{
+		{"visible", nil, ItemAccessInfo{LibraryID: movies, ParentalRating: intp(12)}, RuleVisible},
+		{"disabled", func(p *Policy) { p.IsDisabled = true }, ItemAccessInfo{LibraryID: movies}, RuleUserDisabled},
+		{"other library", nil, ItemAccessInfo{LibraryID: "a656b907eb3a73532e40e44b968d0225"}, RuleLibraryNotEnabled},
+		{"all libraries", func(p *Policy) { p.EnableAllFolders = true }, ItemAccessInfo{LibraryID: "a656b907eb3a73532e40e44b968d0225", ParentalRating: intp(0)}, RuleVisible},
+		{"blocked library", func(p *Policy) { p.EnableAllFolders, p.BlockedMediaFolders = true, []interface{}{movies} }, ItemAccessInfo{LibraryID: movies}, RuleLibraryBlocked},
+		{"enabled by guid", func(p *Policy) { p.EnabledFolders = []string{"5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2"} }, ItemAccessInfo{LibraryID: "3", LibraryGuid: "5c8ce4a3d4c24f5a9b4ad4b3c1a6e0f2", ParentalRating: intp(0)}, RuleVisible},
+		{"excluded subfolder", func(p *Policy) { p.ExcludedSubFolders = []interface{}{movies + "_20"} }, ItemAccessInfo{LibraryID: movies, SubFolderIDs: []string{"20"}}, RuleSubFolderExcluded},
+		{"other subfolder", func(p *Policy) { p.ExcludedSubFolders = []interface{}{movies + "_20"} }, ItemAccessInfo{LibraryID: movies, SubFolderIDs: []string{"21"}, ParentalRating: intp(0)}, RuleVisible},
+		{"blocked tag", nil, ItemAccessInfo{LibraryID: movies, Tags: []string{"horror"}}, RuleBlockedTag},
+		{"allowed tags", func(p *Policy) { p.AllowedTags = []interface{}{"Kids"} }, ItemAccessInfo{LibraryID: movies, ParentalRating: intp(0)}, RuleMissingAllowedTag},
+		{"included tags", func(p *Policy) { p.IsTagBlockingModeInclusive, p.IncludeTags = true, []string{"Kids"} }, ItemAccessInfo{LibraryID: movies, Tags: []string{"kids"}, ParentalRating: intp(0)}, RuleVisible},
+		{"rating", nil, ItemAccessInfo{LibraryID: movies, ParentalRating: intp(17)}, RuleParentalRating},
+		{"unrated movie", nil, ItemAccessInfo{LibraryID: movies, UnratedKind: UnratedMovie}, RuleUnratedBlocked},
+		{"unrated series", nil, ItemAccessInfo{LibraryID: movies, UnratedKind: UnratedSeries}, RuleVisible},
+		{"no max rating", func(p *Policy) { p.MaxParentalRating = nil }, ItemAccessInfo{LibraryID: movies, UnratedKind: UnratedMovie}, RuleVisible},
+	}
+	for _, c := range cases {
+		p := base
+		if c.policy != nil {
+			c.policy(&p)
+		}
+		got := p.ItemVisibility(c.item)
+		if got.Rule != c.want || got.Visible != (c.want == RuleVisible) {
+			t.Errorf("%s: got %+v, want %s", c.name, got, c.want)
+		}
+	}
+}
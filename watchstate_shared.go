@@ -0,0 +1,253 @@
+package mediabrowser
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Item types included in watch state exports.
+var watchStateItemTypes = []string{"Movie", "Series", "Episode"}
+
+// WatchRecord is a user's state for one item, identified by provider IDs so it can be restored on another server.
+type WatchRecord struct {
+	Type        string            `json:"type"` // Movie, Series or Episode.
+	Name        string            `json:"name"`
+	ProviderIDs map[string]string `json:"providerIds"` // e.g. {"Imdb": "tt0113277", "Tmdb": "949"}.
+	// For episodes, to help a human identify them. Not used for matching.
+	SeriesName            string     `json:"seriesName,omitempty"`
+	SeasonNumber          int        `json:"seasonNumber,omitempty"`
+	EpisodeNumber         int        `json:"episodeNumber,omitempty"`
+	Played                bool       `json:"played"`
+	PlayCount             int        `json:"playCount"`
+	LastPlayedDate        *time.Time `json:"lastPlayedDate,omitempty"`
+	PlaybackPositionTicks int64      `json:"playbackPositionTicks"`
+	IsFavorite            bool       `json:"isFavorite"`
+}
+
+// ExportWatchState returns the user's watch state (played, play count, position & favorites) for every movie, series & episode they've touched.
+func (mb *MediaBrowser) ExportWatchState(userID string) ([]WatchRecord, error) {
+	return mb.ExportWatchStateContext(context.Background(), userID)
+}
+
+// ExportWatchStateContext is ExportWatchState with a context controlling the requests.
+func (mb *MediaBrowser) ExportWatchStateContext(ctx context.Context, userID string) ([]WatchRecord, error) {
+	it := mb.ItemIteratorContext(ctx, ItemsQuery{
+		UserID:           userID,
+		IncludeItemTypes: watchStateItemTypes,
+		Recursive:        true,
+		Fields:           []string{"ProviderIds"},
+		Limit:            500,
+	})
+	defer it.Close()
+	records := []WatchRecord{}
+	for it.Next() {
+		item := it.Item()
+		data := item.UserData
+		if data == nil || !(data.Played || data.PlayCount != 0 || data.PlaybackPositionTicks != 0 || data.IsFavorite) {
+			continue
+		}
+		record := WatchRecord{
+			Type:                  item.Type,
+			Name:                  item.Name,
+			ProviderIDs:           item.ProviderIDs,
+			Played:                data.Played,
+			PlayCount:             data.PlayCount,
+			PlaybackPositionTicks: data.PlaybackPositionTicks,
+			IsFavorite:            data.IsFavorite,
+		}
+		if item.Type == "Episode" {
+			record.SeriesName, record.SeasonNumber, record.EpisodeNumber = item.SeriesName, item.ParentIndexNumber, item.IndexNumber
+		}
+		if !data.LastPlayedDate.IsZero() {
+			last := data.LastPlayedDate.Time
+			record.LastPlayedDate = &last
+		}
+		records = append(records, record)
+	}
+	return records, it.Err()
+}
+
+// WatchStateImportResult reports how an ImportWatchState went.
+type WatchStateImportResult struct {
+	Imported  int
+	Unmatched []WatchRecord // Records with no item of the same type and a matching provider ID on the server.
+}
+
+// providerKey identifies an item of the given type by one of its provider IDs, ignoring case.
+func providerKey(itemType, provider, id string) string {
+	return strings.ToLower(itemType + "|" + provider + "|" + id)
+}
+
+// ImportWatchState applies the given records to the user, matching each to an item of the same type with any of the same provider IDs.
+// The user's existing state for matched items is overwritten. If an error occurs, the result so far is returned with it.
+func (mb *MediaBrowser) ImportWatchState(userID string, records []WatchRecord) (WatchStateImportResult, error) {
+	return mb.ImportWatchStateContext(context.Background(), userID, records)
+}
+
+// ImportWatchStateContext is ImportWatchState with a context controlling the requests.
+func (mb *MediaBrowser) ImportWatchStateContext(ctx context.Context, userID string, records []WatchRecord) (WatchStateImportResult, error) {
+	result := WatchStateImportResult{Unmatched: []WatchRecord{}}
+	// Index the items the user can see by provider ID.
+	items := map[string]string{}
+	it := mb.ItemIteratorContext(ctx, ItemsQuery{
+		UserID:           userID,
+		IncludeItemTypes: watchStateItemTypes,
+		Recursive:        true,
+		Fields:           []string{"ProviderIds"},
+		Limit:            500,
+	})
+	defer it.Close()
+	for it.Next() {
+		item := it.Item()
+		for provider, id := range item.ProviderIDs {
+			if id != "" {
+				items[providerKey(item.Type, provider, id)] = item.ID
+			}
+		}
+	}
+	if it.Err() != nil {
+		return result, it.Err()
+	}
+
+	for _, record := range records {
+		itemID := ""
+		// Sorted, so the match doesn't depend on map order.
+		providers := make([]string, 0, len(record.ProviderIDs))
+		for provider := range record.ProviderIDs {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			if id, ok := items[providerKey(record.Type, provider, record.ProviderIDs[provider])]; ok {
+				itemID = id
+				break
+			}
+		}
+		if itemID == "" {
+			result.Unmatched = append(result.Unmatched, record)
+			continue
+		}
+		data := UserItemData{
+			Played:                record.Played,
+			PlayCount:             record.PlayCount,
+			PlaybackPositionTicks: record.PlaybackPositionTicks,
+			IsFavorite:            record.IsFavorite,
+		}
+		if record.LastPlayedDate != nil {
+			data.LastPlayedDate = Time{*record.LastPlayedDate}
+		}
+		if _, err := mb.SetUserItemDataContext(ctx, userID, itemID, data); err != nil {
+			return result, err
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// WriteWatchStateJSON writes records as indented JSON.
+func WriteWatchStateJSON(w io.Writer, records []WatchRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// ReadWatchStateJSON reads records written by WriteWatchStateJSON.
+func ReadWatchStateJSON(r io.Reader) ([]WatchRecord, error) {
+	var records []WatchRecord
+	err := json.NewDecoder(r).Decode(&records)
+	return records, err
+}
+
+var watchStateCSVHeader = []string{"Type", "Name", "Provider IDs", "Series", "Season", "Episode", "Played", "Play Count", "Last Played", "Position Ticks", "Favorite"}
+
+// WriteWatchStateCSV writes records as CSV. Provider IDs are written in one column, like "Imdb=tt0113277;Tmdb=949".
+func WriteWatchStateCSV(w io.Writer, records []WatchRecord) error {
+	out := csv.NewWriter(w)
+	out.Write(watchStateCSVHeader)
+	for _, record := range records {
+		providers := make([]string, 0, len(record.ProviderIDs))
+		for provider, id := range record.ProviderIDs {
+			providers = append(providers, provider+"="+id)
+		}
+		sort.Strings(providers)
+		lastPlayed := ""
+		if record.LastPlayedDate != nil {
+			lastPlayed = record.LastPlayedDate.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{
+			record.Type,
+			record.Name,
+			strings.Join(providers, ";"),
+			record.SeriesName,
+			strconv.Itoa(record.SeasonNumber),
+			strconv.Itoa(record.EpisodeNumber),
+			strconv.FormatBool(record.Played),
+			strconv.Itoa(record.PlayCount),
+			lastPlayed,
+			strconv.FormatInt(record.PlaybackPositionTicks, 10),
+			strconv.FormatBool(record.IsFavorite),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ReadWatchStateCSV reads records written by WriteWatchStateCSV.
+func ReadWatchStateCSV(r io.Reader) ([]WatchRecord, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = len(watchStateCSVHeader)
+	rows, err := in.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	records := []WatchRecord{}
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		record := WatchRecord{Type: row[0], Name: row[1], SeriesName: row[3], ProviderIDs: map[string]string{}}
+		for _, pair := range strings.Split(row[2], ";") {
+			if sep := strings.Index(pair, "="); sep > 0 {
+				record.ProviderIDs[pair[:sep]] = pair[sep+1:]
+			}
+		}
+		line := i + 1
+		fail := func(column string, err error) error {
+			return fmt.Errorf("line %d: invalid %s: %v", line, column, err)
+		}
+		if record.SeasonNumber, err = strconv.Atoi(row[4]); err != nil {
+			return nil, fail("season", err)
+		}
+		if record.EpisodeNumber, err = strconv.Atoi(row[5]); err != nil {
+			return nil, fail("episode", err)
+		}
+		if record.Played, err = strconv.ParseBool(row[6]); err != nil {
+			return nil, fail("played", err)
+		}
+		if record.PlayCount, err = strconv.Atoi(row[7]); err != nil {
+			return nil, fail("play count", err)
+		}
+		if row[8] != "" {
+			last, err := time.Parse(time.RFC3339, row[8])
+			if err != nil {
+				return nil, fail("last played date", err)
+			}
+			record.LastPlayedDate = &last
+		}
+		if record.PlaybackPositionTicks, err = strconv.ParseInt(row[9], 10, 64); err != nil {
+			return nil, fail("position", err)
+		}
+		if record.IsFavorite, err = strconv.ParseBool(row[10]); err != nil {
+			return nil, fail("favorite", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,83 @@
+package mediabrowser
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWatchStateMigration(t *testing.T) {
+	posted := map[string]map[string]interface{}{}
+	mb, _ := newTestServer(t, JellyfinServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/Users/srcsrcsrcsrc/Items":
+			w.Write([]byte(`{"Items":[
+				{"Id":"1","Name":"Heat","Type":"Movie","ProviderIds":{"Imdb":"tt0113277","Tmdb":"949"},
+					"UserData":{"Played":true,"PlayCount":2,"LastPlayedDate":"2024-03-01T20:00:00.0000000Z"}},
+				{"Id":"2","Name":"Pilot","Type":"Episode","SeriesName":"Lost","ParentIndexNumber":1,"IndexNumber":1,"ProviderIds":{"Tvdb":"127131"},
+					"UserData":{"PlaybackPositionTicks":6000000000,"IsFavorite":true}},
+				{"Id":"3","Name":"Untouched","Type":"Movie","ProviderIds":{"Imdb":"tt0000001"},"UserData":{}},
+				{"Id":"4","Name":"Local Only","Type":"Movie","ProviderIds":{},"UserData":{"Played":true}}
+			],"TotalRecordCount":4}`))
+		case r.URL.Path == "/Users/dstdstdstdst/Items":
+			w.Write([]byte(`{"Items":[
+				{"Id":"a","Type":"Movie","ProviderIds":{"Tmdb":"949"}},
+				{"Id":"b","Type":"Episode","ProviderIds":{"Tvdb":"127131"}},
+				{"Id":"c","Type":"Series","ProviderIds":{"Tvdb":"127131"}}
+			],"TotalRecordCount":3}`))
+		case strings.HasPrefix(r.URL.Path, "/Users/dstdstdstdst/Items/"):
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			posted[strings.Split(r.URL.Path, "/")[4]] = body
+			w.Write([]byte(`{}`))
+		}
+	}))
+	mb.Authenticated = true
+
+	records, err := mb.ExportWatchState("srcsrcsrcsrc")
+	if err != nil {
+		t.Fatalf("ExportWatchState failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if r := records[1]; r.SeriesName != "Lost" || r.SeasonNumber != 1 || r.PlaybackPositionTicks != 6000000000 || !r.IsFavorite {
+		t.Errorf("unexpected episode record: %+v", r)
+	}
+
+	var csv bytes.Buffer
+	if err := WriteWatchStateCSV(&csv, records); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadWatchStateCSV(&csv)
+	if err != nil {
+		t.Fatalf("ReadWatchStateCSV failed: %v", err)
+	}
+	if len(read) != 3 || read[0].ProviderIDs["Imdb"] != "tt0113277" || !read[0].LastPlayedDate.Equal(*records[0].LastPlayedDate) {
+		t.Fatalf("CSV didn't round-trip: %+v", read)
+	}
+	var js bytes.Buffer
+	WriteWatchStateJSON(&js, records)
+	if fromJSON, err := ReadWatchStateJSON(&js); err != nil || len(fromJSON) != 3 {
+		t.Fatalf("JSON didn't round-trip: %+v, %v", fromJSON, err)
+	}
+
+	result, err := mb.ImportWatchState("dstdstdstdst", read)
+	if err != nil {
+		t.Fatalf("ImportWatchState failed: %v", err)
+	}
+	if result.Imported != 2 || len(result.Unmatched) != 1 || result.Unmatched[0].Name != "Local Only" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if movie := posted["a"]; movie["Played"] != true || movie["PlayCount"] != 2.0 || movie["LastPlayedDate"] != "2024-03-01T20:00:00Z" {
+		t.Errorf("unexpected data for movie: %v", movie)
+	}
+	if episode := posted["b"]; episode["PlaybackPositionTicks"] != 6e9 || episode["IsFavorite"] != true {
+		t.Errorf("unexpected data for episode: %v", episode)
+	}
+	if _, ok := posted["c"]; ok {
+		t.Errorf("episode state applied to a series")
+	}
+}
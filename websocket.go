@@ -0,0 +1,289 @@
+package mediabrowser
+
+// A minimal WebSocket (RFC 6455) implementation, just enough to talk to the server's event socket without a dependency.
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// Messages from the server are small, so anything bigger than this is probably garbage.
+const wsMaxMessageSize = 32 << 20
+
+var errWSClosed = errors.New("websocket closed")
+
+type wsConn struct {
+	conn      net.Conn
+	br        *bufio.Reader
+	client    bool // Frames sent by a client must be masked.
+	writeLock sync.Mutex
+}
+
+func newWSConn(conn net.Conn, br *bufio.Reader, client bool) *wsConn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &wsConn{conn: conn, br: br, client: client}
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// wsHandshakeError is returned when the server answers the handshake with something other than 101 Switching Protocols.
+type wsHandshakeError struct {
+	status int
+	data   string
+}
+
+func (err wsHandshakeError) Error() string {
+	return fmt.Sprintf("websocket handshake failed (code %d)", err.status)
+}
+
+// dialWebSocket connects and performs the opening handshake. u's scheme should be ws or wss.
+func dialWebSocket(ctx context.Context, u *url.URL, header http.Header, tlsConfig *tls.Config) (*wsConn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	// Abort the handshake if the context is cancelled.
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	ws, err := wsHandshake(conn, u, header, tlsConfig)
+	close(stop)
+	<-stopped
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+func wsHandshake(conn net.Conn, u *url.URL, header http.Header, tlsConfig *tls.Config) (*wsConn, error) {
+	if u.Scheme == "wss" {
+		cfg := &tls.Config{}
+		if tlsConfig != nil {
+			cfg = tlsConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method:     "GET",
+		URL:        u,
+		Host:       u.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return nil, wsHandshakeError{status: resp.StatusCode, data: bodyToString(resp)}
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, errors.New("websocket handshake failed: invalid upgrade response")
+	}
+	conn.SetDeadline(time.Time{})
+	return newWSConn(conn, br, true), nil
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	opcode = h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	length := uint64(h[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxMessageSize {
+		err = fmt.Errorf("websocket frame too large (%d bytes)", length)
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// ReadMessage returns the next text or binary message, answering any pings and reassembling fragments along the way.
+// errWSClosed is returned if the other end closed the connection.
+func (c *wsConn) ReadMessage() (opcode byte, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(wsClose, payload)
+			return 0, nil, errWSClosed
+		case wsContinuation:
+			if len(data)+len(payload) > wsMaxMessageSize {
+				return 0, nil, errors.New("websocket message too large")
+			}
+			data = append(data, payload...)
+		default:
+			opcode, data = op, payload
+		}
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single frame. Safe to call from multiple goroutines.
+func (c *wsConn) WriteMessage(opcode byte, data []byte) error {
+	return c.writeFrame(opcode, data)
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[len(frame)-2:], uint16(n))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a normal closure frame and closes the underlying connection.
+func (c *wsConn) Close() error {
+	c.writeFrame(wsClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}